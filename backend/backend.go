@@ -16,6 +16,11 @@
 package backend
 
 import (
+	"errors"
+	"flag"
+	"math/big"
+	"time"
+
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/logger"
 	"github.com/rs/zerolog"
@@ -47,6 +52,9 @@ func (id ID) String() string {
 	}
 }
 
+// ErrProveTimeout is returned by the prover when it exceeds the budget set with WithTimeout
+var ErrProveTimeout = errors.New("prover exceeded its time budget")
+
 // ProverOption defines option for altering the behaviour of the prover in
 // Prove, ReadAndProve and IsSolved methods. See the descriptions of functions
 // returning instances of this type for implemented options.
@@ -57,6 +65,65 @@ type ProverConfig struct {
 	Force         bool                      // defaults to false
 	HintFunctions map[hint.ID]hint.Function // defaults to all built-in hint functions
 	CircuitLogger zerolog.Logger            // defaults to gnark.Logger
+	CommitOnly    bool                      // defaults to false
+	SolverStats   *SolverStats              // defaults to nil
+	Diagnostics   *ProverDiagnostics        // defaults to nil
+	Challenges    *FixedChallenges          // defaults to nil, INSECURE, see WithInsecureFixedChallenges
+	Nonce         []byte                    // defaults to nil
+	Timeout       time.Duration             // defaults to 0, no timeout
+	PublicWitness *[]big.Int                // defaults to nil
+	MemStats      *MemStats                 // defaults to nil
+	AuditTrail    *ProverAuditTrail         // defaults to nil, leaks the witness, see WithAuditTrail
+	Solution      *[]big.Int                // defaults to nil, leaks the witness, see WithSolution
+	Progress      ProgressFunc              // defaults to nil
+}
+
+// ProgressFunc is called by the prover at the end of each of its phases, see WithProgress.
+type ProgressFunc func(phase string, fraction float64)
+
+// SolverStats holds statistics collected by the constraint system solver, see WithSolverStats.
+type SolverStats struct {
+	NbConstraints          int           // number of constraints evaluated by the solver
+	NbWiresSolved          int           // number of wires computed by the solver, the witness excluded
+	NbCheckOnlyConstraints int           // number of constraints which didn't instantiate a wire (except through hints) and were only checked
+	Duration               time.Duration // time spent in the solver
+}
+
+// MemStats holds the sizes of the largest allocations of the prover, see WithMemStats.
+type MemStats struct {
+	// BigDomainBytes is the peak size of the evaluations on the big domain allocated by the
+	// prover for a proof. They are recycled across proofs, and dominate its memory usage.
+	BigDomainBytes uint64
+
+	// ProvingKeyBigDomainBytes is the size of the evaluations on the big domain cached in the
+	// proving key, shared by the proofs using the same key.
+	ProvingKeyBigDomainBytes uint64
+}
+
+// FixedChallenges holds the PLONK challenges used by the prover in place of the ones derived
+// with Fiat-Shamir, see WithInsecureFixedChallenges.
+type FixedChallenges struct {
+	Beta, Gamma, Alpha, Zeta big.Int
+}
+
+// ProverDiagnostics holds intermediate values computed by the prover, for research and
+// debugging purposes, see WithDiagnostics. They are not part of the proof.
+type ProverDiagnostics struct {
+	// Quotient is the PLONK quotient polynomial h in canonical form, before it is split in
+	// h1, h2, h3. Its size is the size of the big evaluation domain.
+	Quotient []big.Int
+}
+
+// ProverAuditTrail holds the polynomials committed to by the PLONK prover, in canonical form,
+// see WithAuditTrail.
+type ProverAuditTrail struct {
+	// L, R, O, Z are the blinded polynomials whose commitments are the LRO and Z fields of the
+	// proof.
+	L, R, O, Z []big.Int
+
+	// H holds the parts h1, h2, h3 of the quotient, whose commitments are the H field of the
+	// proof.
+	H [3][]big.Int
 }
 
 // NewProverConfig returns a default ProverConfig with given prover options opts
@@ -114,3 +181,164 @@ func WithCircuitLogger(l zerolog.Logger) ProverOption {
 		return nil
 	}
 }
+
+// WithCommitOnly is a prover option that makes the PLONK prover stop once it has committed
+// to the solution, permutation and quotient polynomials. Evaluations and opening proofs are
+// not computed, so the resulting Proof can be used as a pre-commitment but can't be verified
+// on its own.
+func WithCommitOnly() ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.CommitOnly = true
+		return nil
+	}
+}
+
+// WithSolverStats is a prover option that makes the constraint system solver fill stats
+// once it has successfully solved the constraint system. Constraints which are only checked
+// may hint at redundant constraints in the circuit.
+//
+// It is currently only supported by the PLONK prover.
+func WithSolverStats(stats *SolverStats) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.SolverStats = stats
+		return nil
+	}
+}
+
+// WithDiagnostics is a prover option that makes the prover fill diagnostics with intermediate
+// values, such as the PLONK quotient polynomial, so that its divisibility and degree can be
+// checked externally. This increases the memory used by the prover and the output size.
+//
+// It is currently only supported by the PLONK prover.
+func WithDiagnostics(diagnostics *ProverDiagnostics) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Diagnostics = diagnostics
+		return nil
+	}
+}
+
+// WithNonce is a prover option that binds nonce to the Fiat-Shamir transcript, before any other
+// data. The nonce is part of the proof, so that the verifier derives the same challenges.
+//
+// This doesn't affect the soundness of the proof system: it only makes the proof unique to the
+// nonce. The verifier takes the nonce from the proof and doesn't compare it with an expected
+// value, so to prevent a proof from being replayed in another session of a protocol, the caller
+// must check that the nonce of the proof is the one of the session before verifying it.
+//
+// It is currently only supported by the PLONK prover.
+func WithNonce(nonce []byte) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Nonce = nonce
+		return nil
+	}
+}
+
+// WithPublicWitness is a prover option that makes the prover set publicWitness to the public
+// inputs it bound to the Fiat-Shamir transcript, reduced modulo the scalar field of the curve.
+// Verifying the proof against exactly these values avoids mismatches when the caller
+// reconstructs the public inputs differently, for instance from an unreduced assignment.
+//
+// It is currently only supported by the PLONK prover.
+func WithPublicWitness(publicWitness *[]big.Int) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.PublicWitness = publicWitness
+		return nil
+	}
+}
+
+// WithSolution is a prover option that makes the prover set solution to the values of all the
+// wires computed by the solver, ordered as [ public | secret | internal ], so that the caller
+// doesn't need to solve the constraint system a second time, for instance to prove the same
+// witness again with ProveWithSolution.
+//
+// DANGER: the solution holds the secret inputs and every internal wire, it must not be
+// persisted or shared where the zero-knowledge property matters.
+//
+// It is currently only supported by the PLONK prover.
+func WithSolution(solution *[]big.Int) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Solution = solution
+		return nil
+	}
+}
+
+// WithMemStats is a prover option that makes the prover fill stats with the sizes of its
+// largest allocations, to size the proving nodes. The sizes are computed from the lengths of
+// the slices the prover creates, so collecting them has no measurable cost.
+//
+// It is currently only supported by the PLONK prover.
+func WithMemStats(stats *MemStats) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.MemStats = stats
+		return nil
+	}
+}
+
+// WithAuditTrail is a prover option that makes the prover fill trail with the polynomials it
+// commits to, so that an auditor can later recompute the commitments of the proof independently.
+//
+// DANGER: l, r, o are the witness, blinded only by a few random coefficients: whoever reads the
+// trail learns the witness. It is meant for audit contexts only and must not be used in
+// deployments relying on the zero-knowledge property. The prover logs a warning when it is set.
+//
+// It is currently only supported by the PLONK prover.
+func WithAuditTrail(trail *ProverAuditTrail) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.AuditTrail = trail
+		return nil
+	}
+}
+
+// WithProgress is a prover option that makes the prover call progress at the end of each of its
+// phases, with the name of the phase and the approximate fraction of the proof done, for instance
+// to show a progress bar during long proofs.
+//
+// The phases are, in order: "solve" (Prove only), "commit-lro", "quotient", "commit-quotient",
+// "linearize" and "done", whose fraction is 1. A proof generated with WithCommitOnly goes from
+// "commit-quotient" to "done". progress is called from the goroutine calling Prove, at most once
+// per phase, so it doesn't need to be throttled; it must return quickly.
+//
+// It is currently only supported by the PLONK prover.
+func WithProgress(progress ProgressFunc) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Progress = progress
+		return nil
+	}
+}
+
+// WithTimeout is a prover option that aborts the prover with ErrProveTimeout when it runs for
+// longer than timeout, including the time spent in the solver.
+//
+// The budget is checked between the steps of the prover, which are not interrupted: the prover
+// may return up to one step after the deadline. When it aborts, the prover waits for its worker
+// goroutines and releases its buffers before returning.
+//
+// It is currently only supported by the PLONK prover.
+func WithTimeout(timeout time.Duration) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Timeout = timeout
+		return nil
+	}
+}
+
+// WithInsecureFixedChallenges is a prover option that makes the PLONK prover use the given
+// challenges instead of deriving them from the transcript, so that unit tests of the prover
+// internals are deterministic. The values are reduced modulo the scalar field of the curve.
+//
+// DANGER: this breaks the soundness of the proof system, a prover who knows the challenges in
+// advance can forge proofs of false statements. The resulting proofs don't verify with Verify,
+// which always derives the challenges with Fiat-Shamir. This option panics when used outside
+// of a test binary.
+func WithInsecureFixedChallenges(beta, gamma, alpha, zeta *big.Int) ProverOption {
+	if flag.Lookup("test.v") == nil {
+		panic("WithInsecureFixedChallenges can only be used in tests")
+	}
+	return func(opt *ProverConfig) error {
+		opt.Challenges = &FixedChallenges{}
+		opt.Challenges.Beta.Set(beta)
+		opt.Challenges.Gamma.Set(gamma)
+		opt.Challenges.Alpha.Set(alpha)
+		opt.Challenges.Zeta.Set(zeta)
+		return nil
+	}
+}
@@ -17,6 +17,27 @@
 // See also
 //
 // https://eprint.iacr.org/2019/953
+//
+// Side channels
+//
+// The prover is not constant-time, it must not run where its timing or memory accesses can be
+// observed by an adversary who shouldn't learn the witness:
+//
+// 	* the solver is variable-time: hints and divisions branch on the values of the wires, and
+// 	hints may use math/big, which is not constant-time.
+// 	* the commitments and openings use multi-scalar multiplications with the bucket method,
+// 	whose memory accesses depend on the digits of the coefficients of the (blinded) witness
+// 	polynomials.
+// 	* the field multiplication is constant-time with the amd64 ADX assembly only: the generic
+// 	code, used on other architectures, on CPUs without ADX or with the noadx build tag, ends
+// 	with a conditional subtraction.
+//
+// The FFTs, the computation of the quotient and the blinding don't branch on secret values. The
+// only secret-dependent branch of the prover itself is the check for a zero denominator in the
+// permutation accumulator Z, which only fires with negligible probability for honestly derived
+// challenges. With the force flag (backend.IgnoreSolverError), the internal wires are filled
+// with random values when the solver fails: this depends on the validity of the witness, not on
+// its values.
 package plonk
 
 import (
@@ -25,6 +46,7 @@ import (
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/kzg"
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/frontend"
 
 	"github.com/consensys/gnark/backend/witness"
@@ -168,7 +190,38 @@ func Prove(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitness *wi
 	}
 }
 
+// ProveFromMap generates PLONK proof like Prove, with the witness given as a map from the
+// circuit variable names to their values (see witness.Witness.FromMap).
+//
+// It returns an error if a public or secret variable of the circuit is missing from the map.
+func ProveFromMap(ccs frontend.CompiledConstraintSystem, pk ProvingKey, assignment map[string]interface{}, opts ...backend.ProverOption) (Proof, error) {
+	fullWitness, err := witness.New(ccs.CurveID(), ccs.GetSchema())
+	if err != nil {
+		return nil, err
+	}
+	if err := fullWitness.FromMap(assignment); err != nil {
+		return nil, err
+	}
+	return Prove(ccs, pk, fullWitness, opts...)
+}
+
+// ProveWithHints generates PLONK proof like Prove, solving the constraint system with hints in
+// addition to the hint functions registered with hint.Register. A hint in hints replaces a
+// registered one with the same ID.
+func ProveWithHints(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitness *witness.Witness, hints map[hint.ID]hint.Function, opts ...backend.ProverOption) (Proof, error) {
+	withHints := func(opt *backend.ProverConfig) error {
+		for id, f := range hints {
+			opt.HintFunctions[id] = f
+		}
+		return nil
+	}
+	return Prove(ccs, pk, fullWitness, append([]backend.ProverOption{withHints}, opts...)...)
+}
+
 // Verify verifies a PLONK proof, from the proof, preprocessed public data, and public witness.
+//
+// Verify runs under GOOS=js GOARCH=wasm: the multi-exponentiations size their tasks with
+// runtime.NumCPU(), which is 1 there, so the verifier doesn't rely on parallelism.
 func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error {
 
 	switch _proof := proof.(type) {
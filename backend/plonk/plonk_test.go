@@ -0,0 +1,79 @@
+package plonk_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+)
+
+const nbBits = 8
+
+// toBits is a custom hint, not registered with hint.Register, which decomposes its input in
+// nbBits bits
+func toBits(_ ecc.ID, inputs []*big.Int, outputs []*big.Int) error {
+	for i := 0; i < len(outputs); i++ {
+		outputs[i].SetUint64(uint64(inputs[0].Bit(i)))
+	}
+	return nil
+}
+
+type bitsCircuit struct {
+	X frontend.Variable `gnark:",public"`
+}
+
+func (circuit *bitsCircuit) Define(api frontend.API) error {
+	bits, err := api.Compiler().NewHint(toBits, nbBits, circuit.X)
+	if err != nil {
+		return err
+	}
+	var acc frontend.Variable = 0
+	for i := nbBits - 1; i >= 0; i-- {
+		api.AssertIsBoolean(bits[i])
+		acc = api.Add(api.Mul(acc, 2), bits[i])
+	}
+	api.AssertIsEqual(circuit.X, acc)
+	return nil
+}
+
+func TestProveWithHints(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &bitsCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srs, err := test.NewKZGSRS(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := plonk.Setup(ccs, srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment := bitsCircuit{X: 179}
+	fullWitness, err := frontend.NewWitness(&assignment, ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := frontend.NewWitness(&assignment, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := plonk.Prove(ccs, pk, fullWitness); err == nil {
+		t.Fatal("solving should fail without the toBits hint")
+	}
+
+	proof, err := plonk.ProveWithHints(ccs, pk, fullWitness, map[hint.ID]hint.Function{hint.UUID(toBits): toBits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
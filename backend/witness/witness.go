@@ -208,6 +208,42 @@ func (w *Witness) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// FromMap sets the full witness from an assignment given as a map indexed by the circuit
+// variable names, as they appear in the Schema (nested structures are nested maps).
+//
+// Values can be of any type accepted by the JSON decoding of a field element (integers, or
+// strings in decimal or hex form). It returns an error if a variable is missing from the map,
+// or if the map contains a name which is not in the Schema.
+func (w *Witness) FromMap(assignment map[string]interface{}) error {
+	if w.Schema == nil {
+		return errMissingSchema
+	}
+	data, err := json.Marshal(assignment)
+	if err != nil {
+		return err
+	}
+	v, err := newVector(w.CurveID)
+	if err != nil {
+		return err
+	}
+
+	typ := v.Type()
+	instance := w.Schema.Instantiate(reflect.PtrTo(typ))
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(instance); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWitness, err)
+	}
+
+	// missing variables are nil and make FromAssignment fail with the variable name
+	if _, err := v.FromAssignment(instance, reflect.PtrTo(typ), false); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWitness, err)
+	}
+	w.Vector = v
+	return nil
+}
+
 func (w *Witness) toAssignment(to interface{}, toLeafType reflect.Type) error {
 	if w.Schema == nil {
 		return errMissingSchema
@@ -129,6 +129,41 @@ func TestPublic(t *testing.T) {
 	assert.Equal("8000", (*wt)[1].String())
 }
 
+func TestFromMap(t *testing.T) {
+	assert := require.New(t)
+
+	var assignment circuit
+	assignment.X = new(fr.Element).SetInt64(42)
+	assignment.Y = new(fr.Element).SetInt64(8000)
+	assignment.E = new(fr.Element).SetInt64(1)
+
+	expected, err := New(ecc.BN254, nil)
+	assert.NoError(err)
+	expected.Schema, err = expected.Vector.FromAssignment(&assignment, tVariable, false)
+	assert.NoError(err)
+
+	w, err := New(ecc.BN254, expected.Schema)
+	assert.NoError(err)
+	assert.NoError(w.FromMap(map[string]interface{}{"X": 42, "Y": "8000", "E": 1}))
+	assert.Equal(expected.Vector, w.Vector)
+
+	// missing secret variable
+	w, err = New(ecc.BN254, expected.Schema)
+	assert.NoError(err)
+	err = w.FromMap(map[string]interface{}{"X": 42, "Y": 8000})
+	assert.ErrorIs(err, ErrInvalidWitness)
+	assert.Contains(err.Error(), "E")
+
+	// missing public variable
+	err = w.FromMap(map[string]interface{}{"X": 42, "E": 1})
+	assert.ErrorIs(err, ErrInvalidWitness)
+	assert.Contains(err.Error(), "Y")
+
+	// unknown variable
+	err = w.FromMap(map[string]interface{}{"X": 42, "Y": 8000, "E": 1, "Z": 2})
+	assert.ErrorIs(err, ErrInvalidWitness)
+}
+
 var tVariable reflect.Type
 
 func init() {
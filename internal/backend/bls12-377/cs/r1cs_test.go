@@ -19,13 +19,17 @@ package cs_test
 import (
 	"bytes"
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/internal/backend/circuits"
 	"reflect"
 	"testing"
 
 	"github.com/consensys/gnark/internal/backend/bls12-377/cs"
+
+	bls12_377witness "github.com/consensys/gnark/internal/backend/bls12-377/witness"
 )
 
 func TestSerialization(t *testing.T) {
@@ -152,3 +156,70 @@ func BenchmarkSolve(b *testing.B) {
 		_ = ccs.IsSolved(witness)
 	}
 }
+
+type solverStatsCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *solverStatsCircuit) Define(api frontend.API) error {
+	x2 := api.Mul(circuit.X, circuit.X)
+	api.AssertIsEqual(x2, circuit.Y)
+	return nil
+}
+
+func TestSparseR1CSSolverStats(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BLS12_377, scs.NewBuilder, &solverStatsCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+
+	tVariable := reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
+	w := bls12_377witness.Witness{}
+	if _, err := w.FromAssignment(&solverStatsCircuit{X: 3, Y: 9}, tVariable, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats backend.SolverStats
+	opt, err := backend.NewProverConfig(backend.WithSolverStats(&stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spr.Solve(w, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.NbConstraints != len(spr.Constraints) {
+		t.Fatalf("expected %d constraints, got %d", len(spr.Constraints), stats.NbConstraints)
+	}
+	if stats.NbWiresSolved != spr.NbInternalVariables {
+		t.Fatalf("expected %d solved wires, got %d", spr.NbInternalVariables, stats.NbWiresSolved)
+	}
+	// the assertion doesn't instantiate any wire
+	if stats.NbCheckOnlyConstraints != 1 {
+		t.Fatalf("expected 1 check only constraint, got %d", stats.NbCheckOnlyConstraints)
+	}
+}
+
+func TestPlonkStats(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BLS12_377, scs.NewBuilder, &solverStatsCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+
+	// rows: the placeholder of Y, X*X = v and v = Y (its unused o slot references wire 0, Y)
+	// copies: Y at 3 positions, X and v at 2 each, 7 positions for 3 wires
+	expected := cs.Stats{
+		NbConstraints:       2,
+		NbPublicVariables:   1,
+		NbSecretVariables:   1,
+		NbInternalVariables: 1,
+		NbRows:              3,
+		NbCopyConstraints:   4,
+	}
+	if stats := spr.PlonkStats(); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+}
@@ -27,10 +27,17 @@ import (
 
 	bls12_377plonk "github.com/consensys/gnark/internal/backend/bls12-377/plonk"
 
-	"bytes"
 	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/kzg"
+
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/fft"
+	"io"
 	"math/big"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
@@ -39,6 +46,196 @@ import (
 	"github.com/consensys/gnark/frontend/cs/scs"
 )
 
+func TestProvingKeyFreeScratch(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+	expected := make([]fr.Element, len(pk.EvaluationPermutationBigDomainBitReversed))
+	copy(expected, pk.EvaluationPermutationBigDomainBitReversed)
+	expectedQk := make([]fr.Element, len(pk.EvaluationQkIncompleteDomainBigBitReversed))
+	copy(expectedQk, pk.EvaluationQkIncompleteDomainBigBitReversed)
+	expectedSelectors := make([]fr.Element, len(pk.EvaluationSelectorsDomainBigBitReversed))
+	copy(expectedSelectors, pk.EvaluationSelectorsDomainBigBitReversed)
+
+	pk.FreeScratch()
+	if pk.EvaluationPermutationBigDomainBitReversed != nil || pk.EvaluationQkIncompleteDomainBigBitReversed != nil ||
+		pk.EvaluationSelectorsDomainBigBitReversed != nil {
+		t.Fatal("FreeScratch should release the big domain evaluations")
+	}
+
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, pk.EvaluationPermutationBigDomainBitReversed) ||
+		!reflect.DeepEqual(expectedQk, pk.EvaluationQkIncompleteDomainBigBitReversed) ||
+		!reflect.DeepEqual(expectedSelectors, pk.EvaluationSelectorsDomainBigBitReversed) {
+		t.Fatal("big domain evaluations are not rebuilt identically by Prove")
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestProveConcurrent runs Prove concurrently on keys whose evaluations on the big domain are
+// missing, run it with -race
+func TestProveConcurrent(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	// a key read back from its encoding doesn't have them either
+	var buf bytes.Buffer
+	if _, err := pk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded bls12_377plonk.ProvingKey
+	if _, err := decoded.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.InitKZG(vk.KZGSRS); err != nil {
+		t.Fatal(err)
+	}
+	pk.FreeScratch()
+
+	for _, key := range []*bls12_377plonk.ProvingKey{pk, &decoded} {
+		const nbProvers = 4
+		errs := make(chan error, nbProvers)
+		for i := 0; i < nbProvers; i++ {
+			go func() {
+				proof, err := bls12_377plonk.Prove(spr, key, fullWitness, backend.ProverConfig{})
+				if err == nil {
+					err = bls12_377plonk.Verify(proof, vk, publicWitness)
+				}
+				errs <- err
+			}()
+		}
+		for i := 0; i < nbProvers; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestProveWithSolution(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	solution, err := spr.Solve(fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bls12_377plonk.ProveWithSolution(spr, pk, solution[1:], backend.ProverConfig{}); err == nil {
+		t.Fatal("ProveWithSolution should reject a solution of the wrong size")
+	}
+
+	proof, err := bls12_377plonk.ProveWithSolution(spr, pk, solution, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProveSolution(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	var solved []big.Int
+	if _, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{Solution: &solved}); err != nil {
+		t.Fatal(err)
+	}
+	nbVariables := spr.NbInternalVariables + spr.NbSecretVariables + spr.NbPublicVariables
+	if len(solved) != nbVariables {
+		t.Fatalf("expected %d wires, got %d", nbVariables, len(solved))
+	}
+
+	// prove again from the solution, without solving
+	solution := make([]fr.Element, len(solved))
+	for i := range solved {
+		solution[i].SetBigInt(&solved[i])
+	}
+	for i := range fullWitness {
+		if !solution[i].Equal(&fullWitness[i]) {
+			t.Fatalf("wire %d should be the input %d of the witness", i, i)
+		}
+	}
+	proof, err := bls12_377plonk.ProveWithSolution(spr, pk, solution, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPermutationPolys(t *testing.T) {
+	_, pk, _, _, _ := setupSmallReferenceCircuit(t)
+
+	s1, s2, s3 := pk.PermutationPolys()
+
+	// re-evaluating the canonical forms on the big domain (coset) must give the stored evaluations
+	n := pk.Domain[1].Cardinality
+	evaluations := make([]fr.Element, 3*n)
+	copy(evaluations, s1)
+	copy(evaluations[n:], s2)
+	copy(evaluations[2*n:], s3)
+	pk.Domain[1].FFT(evaluations[:n], fft.DIF, true)
+	pk.Domain[1].FFT(evaluations[n:2*n], fft.DIF, true)
+	pk.Domain[1].FFT(evaluations[2*n:], fft.DIF, true)
+
+	if !reflect.DeepEqual(evaluations, pk.EvaluationPermutationBigDomainBitReversed) {
+		t.Fatal("permutation polynomials don't match the big domain evaluations")
+	}
+
+	// the returned polynomials must not alias the proving key
+	if &s1[0] == &pk.S1Canonical[0] || &s2[0] == &pk.S2Canonical[0] || &s3[0] == &pk.S3Canonical[0] {
+		t.Fatal("PermutationPolys should return a copy")
+	}
+}
+
+func TestBigDomainCosetShift(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	if err := pk.SetBigDomainCosetShift(fr.One()); err == nil {
+		t.Fatal("a coset shift in the big domain should be rejected")
+	}
+
+	for _, shift := range []uint64{7, 11} {
+		var cosetShift fr.Element
+		cosetShift.SetUint64(shift)
+		if err := pk.SetBigDomainCosetShift(cosetShift); err != nil {
+			t.Fatal(err)
+		}
+		proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+			t.Fatalf("coset shift %d: %v", shift, err)
+		}
+	}
+}
+
+func TestProveCommitOnly(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	opt, err := backend.NewProverConfig(backend.WithCommitOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.H[0].IsInfinity() || proof.Z.IsInfinity() {
+		t.Fatal("commitments should be computed")
+	}
+	if len(proof.BatchedProof.ClaimedValues) != 0 {
+		t.Fatal("opening proofs should not be computed")
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err == nil {
+		t.Fatal("a commit only proof should not verify")
+	}
+}
+
 //--------------------//
 //     benches		  //
 //--------------------//
@@ -87,6 +284,57 @@ func referenceCircuit() (frontend.CompiledConstraintSystem, frontend.Circuit, *k
 	return ccs, &good, srs
 }
 
+// setupSmallReferenceCircuit runs Setup on smallReferenceCircuit, it returns the constraint
+// system, the keys, and the full and public witnesses of the valid assignment. The srs is vk.KZGSRS.
+func setupSmallReferenceCircuit(t *testing.T) (*cs.SparseR1CS, *bls12_377plonk.ProvingKey, *bls12_377plonk.VerifyingKey, bls12_377witness.Witness, bls12_377witness.Witness) {
+	t.Helper()
+	ccs, _solution, srs := smallReferenceCircuit()
+	spr := ccs.(*cs.SparseR1CS)
+	fullWitness := bls12_377witness.Witness{}
+	if _, err := fullWitness.FromAssignment(_solution, tVariable, false); err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bls12_377witness.Witness{}
+	if _, err := publicWitness.FromAssignment(_solution, tVariable, true); err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := bls12_377plonk.Setup(spr, srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spr, pk, vk, fullWitness, publicWitness
+}
+
+func smallReferenceCircuit() (frontend.CompiledConstraintSystem, frontend.Circuit, *kzg.SRS) {
+	const nbConstraints = 10
+	circuit := refCircuit{
+		nbConstraints: nbConstraints,
+	}
+	ccs, err := frontend.Compile(curve.ID, scs.NewBuilder, &circuit)
+	if err != nil {
+		panic(err)
+	}
+
+	var good refCircuit
+	good.X = (2)
+
+	// compute expected Y
+	var expectedY fr.Element
+	expectedY.SetUint64(2)
+
+	for i := 0; i < nbConstraints; i++ {
+		expectedY.Mul(&expectedY, &expectedY)
+	}
+
+	good.Y = (expectedY)
+	srs, err := kzg.NewSRS(ecc.NextPowerOfTwo(nbConstraints)+3, new(big.Int).SetUint64(42))
+	if err != nil {
+		panic(err)
+	}
+
+	return ccs, &good, srs
+}
+
 func BenchmarkSetup(b *testing.B) {
 	ccs, _, srs := referenceCircuit()
 
@@ -112,6 +360,12 @@ func BenchmarkProver(b *testing.B) {
 		b.Fatal(err)
 	}
 
+	// sustained proving: report the time spent in GC pauses per proof
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, err = bls12_377plonk.Prove(ccs.(*cs.SparseR1CS), pk, fullWitness, backend.ProverConfig{})
@@ -119,6 +373,11 @@ func BenchmarkProver(b *testing.B) {
 			b.Fatal(err)
 		}
 	}
+	b.StopTimer()
+
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
 }
 
 func BenchmarkVerifier(b *testing.B) {
@@ -225,3 +484,508 @@ var tVariable reflect.Type
 func init() {
 	tVariable = reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
 }
+
+func TestScalarFieldMismatch(t *testing.T) {
+	spr, pk, vk, fullWitness, _ := setupSmallReferenceCircuit(t)
+
+	// pretend spr was compiled for a curve with another scalar field
+	other := ecc.BN254
+	if curve.ID == ecc.BN254 {
+		other = ecc.BLS12_381
+	}
+	spr.SparseR1CS.CurveID = other
+
+	if _, _, err := bls12_377plonk.Setup(spr, vk.KZGSRS); err == nil {
+		t.Fatal("Setup should reject a constraint system compiled for another scalar field")
+	}
+	if _, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{}); err == nil {
+		t.Fatal("Prove should reject a constraint system compiled for another scalar field")
+	}
+}
+
+func TestExtractPublicWitness(t *testing.T) {
+	spr, pk, vk, fullWitness, _ := setupSmallReferenceCircuit(t)
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicWitness, err := bls12_377plonk.ExtractPublicWitness(fullWitness, spr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicWitness) != spr.NbPublicVariables {
+		t.Fatal("unexpected public witness size")
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bls12_377plonk.ExtractPublicWitness(fullWitness[:len(fullWitness)-1], spr); err == nil {
+		t.Fatal("a witness of the wrong size should be rejected")
+	}
+}
+
+func TestVerifyPublicWitnessLength(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+	for name, w := range map[string]bls12_377witness.Witness{
+		"too short": publicWitness[:len(publicWitness)-1],
+		"too long":  fullWitness[:spr.NbPublicVariables+1],
+		"empty":     nil,
+	} {
+		if err := bls12_377plonk.Verify(proof, vk, w); !errors.Is(err, bls12_377plonk.ErrPublicWitnessLength) {
+			t.Fatalf("%s: expected ErrPublicWitnessLength, got %v", name, err)
+		}
+	}
+}
+
+func TestSetupWithCosetShift(t *testing.T) {
+	// the default coset shift, and a random one
+	spr, pkDefault, vkDefault, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+	srs := vkDefault.KZGSRS
+
+	if _, _, err := bls12_377plonk.SetupWithCosetShift(spr, srs, fr.One()); err == nil {
+		t.Fatal("a coset shift in the big domain should be rejected")
+	}
+
+	var cosetShift fr.Element
+	cosetShift.SetRandom()
+	pk, vk, err := bls12_377plonk.SetupWithCosetShift(spr, srs, cosetShift)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vk.CosetShift.Equal(&cosetShift) || !pk.Domain[1].FrMultiplicativeGen.Equal(&cosetShift) {
+		t.Fatal("the coset shift should be the one of the setup")
+	}
+
+	proofDefault, err := bls12_377plonk.Prove(spr, pkDefault, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proofDefault, vkDefault, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vkDefault, publicWitness); err == nil {
+		t.Fatal("a proof shouldn't verify with the key of another coset shift")
+	}
+
+	// the coset shift survives the serialization of the verifying key
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var reconstructed bls12_377plonk.VerifyingKey
+	if _, err := reconstructed.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconstructed.InitKZG(srs); err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, &reconstructed, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	const nbProofs = 3
+	proofs := make([]*bls12_377plonk.Proof, nbProofs)
+	publicWitnesses := make([]bls12_377witness.Witness, nbProofs)
+	for i := 0; i < nbProofs; i++ {
+		proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs[i] = proof
+		publicWitnesses[i] = publicWitness
+	}
+
+	for i, err := range bls12_377plonk.VerifyBatch(proofs, vk, publicWitnesses) {
+		if err != nil {
+			t.Fatalf("proof %d: %v", i, err)
+		}
+	}
+
+	// tamper with one proof, only its verification must fail
+	tampered := *proofs[1]
+	tampered.ZShiftedOpening.ClaimedValue.SetOne()
+	proofs[1] = &tampered
+	errs := bls12_377plonk.VerifyBatch(proofs, vk, publicWitnesses)
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatal("valid proofs of the batch should verify")
+	}
+	if errs[1] == nil {
+		t.Fatal("tampered proof of the batch should not verify")
+	}
+
+	errs = bls12_377plonk.VerifyBatch(proofs, vk, publicWitnesses[:1])
+	if len(errs) != nbProofs {
+		t.Fatal("expected one error per proof")
+	}
+	for _, err := range errs {
+		if err == nil {
+			t.Fatal("mismatched number of proofs and public witnesses should be rejected")
+		}
+	}
+}
+
+func TestProvePublicWitness(t *testing.T) {
+	spr, pk, vk, fullWitness, _ := setupSmallReferenceCircuit(t)
+
+	var bound []big.Int
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{PublicWitness: &bound})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bound) != spr.NbPublicVariables {
+		t.Fatalf("expected %d public inputs, got %d", spr.NbPublicVariables, len(bound))
+	}
+
+	publicWitness := make(bls12_377witness.Witness, len(bound))
+	for i := range bound {
+		publicWitness[i].SetBigInt(&bound[i])
+		if !publicWitness[i].Equal(&fullWitness[i]) {
+			t.Fatalf("public input %d doesn't match the witness", i)
+		}
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProveMemStats(t *testing.T) {
+	spr, pk, _, fullWitness, _ := setupSmallReferenceCircuit(t)
+
+	var stats backend.MemStats
+	if _, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{MemStats: &stats}); err != nil {
+		t.Fatal(err)
+	}
+
+	// l, r, o, z, L₁, the 3 identities of the quotient and h are alive at the same time
+	n := pk.Domain[1].Cardinality
+	if expected := 9 * n * fr.Bytes; stats.BigDomainBytes != expected {
+		t.Fatalf("expected %d bytes on the big domain, got %d", expected, stats.BigDomainBytes)
+	}
+	// s1, s2, s3, ql, qr, qm, qo and the incomplete qk
+	if expected := 8 * n * fr.Bytes; stats.ProvingKeyBigDomainBytes != expected {
+		t.Fatalf("expected %d bytes cached in the proving key, got %d", expected, stats.ProvingKeyBigDomainBytes)
+	}
+}
+
+func TestEstimateProofSize(t *testing.T) {
+	spr, pk, vk, fullWitness, _ := setupSmallReferenceCircuit(t)
+
+	estimate := bls12_377plonk.EstimateProofSize(vk)
+	for _, nonce := range [][]byte{nil, []byte("session 42")} {
+		proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{Nonce: nonce})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := proof.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != estimate+len(nonce) {
+			t.Fatalf("nonce of %d bytes: estimated %d bytes, got %d", len(nonce), estimate+len(nonce), buf.Len())
+		}
+	}
+}
+
+func TestProveAuditTrail(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	var trail backend.ProverAuditTrail
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{AuditTrail: &trail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	// an auditor recomputes the commitments of the proof from the trail
+	commit := func(p []big.Int) curve.G1Affine {
+		coeffs := make([]fr.Element, len(p))
+		for i := range p {
+			coeffs[i].SetBigInt(&p[i])
+		}
+		digest, err := kzg.Commit(coeffs, vk.KZGSRS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return digest
+	}
+	expected := []struct {
+		name       string
+		poly       []big.Int
+		commitment curve.G1Affine
+	}{
+		{"l", trail.L, proof.LRO[0]},
+		{"r", trail.R, proof.LRO[1]},
+		{"o", trail.O, proof.LRO[2]},
+		{"z", trail.Z, proof.Z},
+		{"h1", trail.H[0], proof.H[0]},
+		{"h2", trail.H[1], proof.H[1]},
+		{"h3", trail.H[2], proof.H[2]},
+	}
+	for _, e := range expected {
+		if c := commit(e.poly); !c.Equal(&e.commitment) {
+			t.Fatalf("commitment to %s doesn't match the proof", e.name)
+		}
+	}
+}
+
+// The opening points are not part of the proof: Verify re-derives ζ and passes ζ, μζ to the kzg
+// verification, so an opening at another point doesn't verify.
+func TestVerifyRejectsOpeningPointSwap(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+
+	var trail backend.ProverAuditTrail
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{AuditTrail: &trail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scalars, _, err := proof.ToVerifierInputs(vk, publicWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeta := scalars[3]
+	z := make([]fr.Element, len(trail.Z))
+	for i := range trail.Z {
+		z[i].SetBigInt(&trail.Z[i])
+	}
+
+	// a valid opening of z, at ζ instead of μζ
+	swapped, err := kzg.Open(z, zeta, vk.KZGSRS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	honest := proof.ZShiftedOpening
+	proof.ZShiftedOpening = swapped
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err == nil {
+		t.Fatal("an opening of z at ζ in place of μζ should be rejected")
+	}
+
+	// keeping the claimed value z(μζ), only the kzg verification at μζ can reject it
+	proof.ZShiftedOpening.ClaimedValue = honest.ClaimedValue
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err == nil {
+		t.Fatal("the quotient of an opening at ζ should be rejected at μζ")
+	}
+
+	// the opening at μζ verifies
+	proof.ZShiftedOpening = honest
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyBytes(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(v io.WriterTo) []byte {
+		var buf bytes.Buffer
+		if _, err := v.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	proofBytes, vkBytes, srsBytes, publicWitnessBytes := encode(proof), encode(vk), encode(vk.KZGSRS), encode(&publicWitness)
+
+	if err := bls12_377plonk.VerifyBytes(proofBytes, vkBytes, srsBytes, publicWitnessBytes); err != nil {
+		t.Fatal(err)
+	}
+
+	// each step of the decoding reports the input it failed on
+	expectError := func(prefix string, err error) {
+		t.Helper()
+		if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+			t.Fatalf("expected an error starting with %q, got %v", prefix, err)
+		}
+	}
+	expectError("invalid proof", bls12_377plonk.VerifyBytes(proofBytes[:len(proofBytes)-1], vkBytes, srsBytes, publicWitnessBytes))
+	expectError("invalid verifying key", bls12_377plonk.VerifyBytes(proofBytes, append(vkBytes, 0), srsBytes, publicWitnessBytes))
+	expectError("invalid kzg srs", bls12_377plonk.VerifyBytes(proofBytes, vkBytes, nil, publicWitnessBytes))
+	expectError("invalid public witness", bls12_377plonk.VerifyBytes(proofBytes, vkBytes, srsBytes, publicWitnessBytes[:3]))
+
+	// a well formed public witness with another value doesn't verify
+	var other fr.Element
+	other.SetUint64(42)
+	wrongWitness := bls12_377witness.Witness{other}
+	if err := bls12_377plonk.VerifyBytes(proofBytes, vkBytes, srsBytes, encode(&wrongWitness)); err == nil {
+		t.Fatal("proof should not verify with another public witness")
+	}
+}
+
+func TestProveProgress(t *testing.T) {
+	spr, pk, _, fullWitness, _ := setupSmallReferenceCircuit(t)
+
+	for _, commitOnly := range []bool{false, true} {
+		var phases []string
+		last := 0.0
+		progress := func(phase string, fraction float64) {
+			if fraction <= last || fraction > 1 {
+				t.Fatalf("phase %s: fraction %f should increase up to 1", phase, fraction)
+			}
+			last = fraction
+			phases = append(phases, phase)
+		}
+		opt := backend.ProverConfig{Progress: progress, CommitOnly: commitOnly}
+		if _, err := bls12_377plonk.Prove(spr, pk, fullWitness, opt); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{"solve", "commit-lro", "quotient", "commit-quotient", "linearize", "done"}
+		if commitOnly {
+			expected = []string{"solve", "commit-lro", "quotient", "commit-quotient", "done"}
+		}
+		if !reflect.DeepEqual(phases, expected) {
+			t.Fatalf("commit only %t: expected phases %v, got %v", commitOnly, expected, phases)
+		}
+		if last != 1 {
+			t.Fatalf("the last fraction should be 1, got %f", last)
+		}
+	}
+}
+
+// TestVerifyRejectsMutatedProof checks that Verify rejects a valid proof as soon as any one of its
+// fields is modified. Everything in a KZG plonk proof is checked by the verifier: the commitments,
+// the opening proofs, the claimed values and the nonce. There is no Merkle path nor FRI layer.
+func TestVerifyRejectsMutatedProof(t *testing.T) {
+	spr, pk, vk, fullWitness, publicWitness := setupSmallReferenceCircuit(t)
+	proof, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{Nonce: []byte("nonce")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bls12_377plonk.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	// shift adds the generator of G1 to p, the result stays in the correct subgroup
+	_, _, g1, _ := curve.Generators()
+	shift := func(p *curve.G1Affine) {
+		var j curve.G1Jac
+		j.FromAffine(p)
+		j.AddMixed(&g1)
+		p.FromJacobian(&j)
+	}
+	one := fr.One()
+
+	type mutation struct {
+		name   string
+		mutate func(*bls12_377plonk.Proof)
+	}
+	var mutations []mutation
+	for i := 0; i < 3; i++ {
+		i := i
+		mutations = append(mutations,
+			mutation{fmt.Sprintf("LRO[%d]", i), func(p *bls12_377plonk.Proof) { shift(&p.LRO[i]) }},
+			mutation{fmt.Sprintf("H[%d]", i), func(p *bls12_377plonk.Proof) { shift(&p.H[i]) }},
+		)
+	}
+	mutations = append(mutations,
+		mutation{"Z", func(p *bls12_377plonk.Proof) { shift(&p.Z) }},
+		mutation{"BatchedProof.H", func(p *bls12_377plonk.Proof) { shift(&p.BatchedProof.H) }},
+		mutation{"ZShiftedOpening.H", func(p *bls12_377plonk.Proof) { shift(&p.ZShiftedOpening.H) }},
+		mutation{"ZShiftedOpening.ClaimedValue", func(p *bls12_377plonk.Proof) {
+			p.ZShiftedOpening.ClaimedValue.Add(&p.ZShiftedOpening.ClaimedValue, &one)
+		}},
+		mutation{"BatchedProof.ClaimedValues (missing)", func(p *bls12_377plonk.Proof) {
+			p.BatchedProof.ClaimedValues = p.BatchedProof.ClaimedValues[:len(p.BatchedProof.ClaimedValues)-1]
+		}},
+		mutation{"Nonce", func(p *bls12_377plonk.Proof) { p.Nonce[0] ^= 1 }},
+		mutation{"Nonce (missing)", func(p *bls12_377plonk.Proof) { p.Nonce = nil }},
+	)
+	for i := range proof.BatchedProof.ClaimedValues {
+		i := i
+		mutations = append(mutations, mutation{fmt.Sprintf("BatchedProof.ClaimedValues[%d]", i), func(p *bls12_377plonk.Proof) {
+			p.BatchedProof.ClaimedValues[i].Add(&p.BatchedProof.ClaimedValues[i], &one)
+		}})
+	}
+
+	// the batched opening binds each claimed value to its commitment: swapping the openings of two
+	// polynomials must be detected, even when the swap is consistent with the other claimed values
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		for j := i + 1; j < len(proof.BatchedProof.ClaimedValues); j++ {
+			i, j := i, j
+			mutations = append(mutations, mutation{fmt.Sprintf("swap BatchedProof.ClaimedValues[%d] and [%d]", i, j), func(p *bls12_377plonk.Proof) {
+				p.BatchedProof.ClaimedValues[i], p.BatchedProof.ClaimedValues[j] = p.BatchedProof.ClaimedValues[j], p.BatchedProof.ClaimedValues[i]
+			}})
+		}
+	}
+	mutations = append(mutations, mutation{"swap LRO[0] and LRO[1], and their openings", func(p *bls12_377plonk.Proof) {
+		p.LRO[0], p.LRO[1] = p.LRO[1], p.LRO[0]
+		p.BatchedProof.ClaimedValues[2], p.BatchedProof.ClaimedValues[3] = p.BatchedProof.ClaimedValues[3], p.BatchedProof.ClaimedValues[2]
+	}})
+
+	for _, m := range mutations {
+		t.Run(m.name, func(t *testing.T) {
+			// deep copy, the mutations must not leak into the other cases
+			var buf bytes.Buffer
+			if _, err := proof.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			var mutated bls12_377plonk.Proof
+			if _, err := mutated.ReadFrom(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			m.mutate(&mutated)
+			if err := bls12_377plonk.Verify(&mutated, vk, publicWitness); err == nil {
+				t.Fatal("verifier accepted a mutated proof")
+			}
+		})
+	}
+}
+
+func TestCircuitDigestMismatch(t *testing.T) {
+	spr, pk, vk, fullWitness, _ := setupSmallReferenceCircuit(t)
+	solution, err := spr.Solve(fullWitness, backend.ProverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the serialized key still matches spr
+	var buf bytes.Buffer
+	if _, err := pk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var reconstructed bls12_377plonk.ProvingKey
+	if _, err := reconstructed.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconstructed.InitKZG(vk.KZGSRS); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bls12_377plonk.ProveWithSolution(spr, &reconstructed, solution, backend.ProverConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reorder the constraints, as a regenerated circuit would
+	spr.Constraints[0], spr.Constraints[1] = spr.Constraints[1], spr.Constraints[0]
+	if _, err := bls12_377plonk.Prove(spr, pk, fullWitness, backend.ProverConfig{}); err == nil {
+		t.Fatal("Prove should reject a constraint system which doesn't match the proving key")
+	}
+	if _, err := bls12_377plonk.ProveWithSolution(spr, pk, solution, backend.ProverConfig{}); err == nil {
+		t.Fatal("ProveWithSolution should reject a constraint system which doesn't match the proving key")
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/consensys/gnark/backend"
@@ -126,6 +127,15 @@ func (cs *SparseR1CS) Solve(witness []fr.Element, opt backend.ProverConfig) ([]f
 
 	log.Debug().Dur("took", time.Since(start)).Msg("constraint system solver done")
 
+	if opt.SolverStats != nil {
+		*opt.SolverStats = backend.SolverStats{
+			NbConstraints:          len(cs.Constraints),
+			NbWiresSolved:          int(solution.nbSolved) - len(witness),
+			NbCheckOnlyConstraints: int(solution.nbCheckOnly),
+			Duration:               time.Since(start),
+		}
+	}
+
 	return solution.values, nil
 
 }
@@ -300,6 +310,7 @@ func (cs *SparseR1CS) solveConstraint(c compiled.SparseR1C, solution *solution,
 	if lro == -1 {
 		// no unsolved wire
 		// can happen if the constraint contained only hint wires.
+		atomic.AddUint64(&solution.nbCheckOnly, 1)
 		return nil
 	}
 	if lro == 1 { // we solve for R: u1L+u2R+u3LR+u4O+k=0 => R(u2+u3L)+u1L+u4O+k = 0
@@ -502,6 +513,60 @@ func (cs *SparseR1CS) GetNbCoefficients() int {
 	return len(cs.Coefficients)
 }
 
+// Stats holds the sizes of a SparseR1CS which determine the cost of a PLONK setup and proof,
+// see PlonkStats.
+type Stats struct {
+	NbConstraints       int
+	NbPublicVariables   int
+	NbSecretVariables   int
+	NbInternalVariables int
+
+	// NbRows is the number of rows of the PLONK trace, one per public input then one per
+	// constraint. The size of the small domain of the setup is the next power of two.
+	NbRows int
+
+	// NbCopyConstraints is the number of equalities between two positions of the l, r, o
+	// columns of the trace which the permutation enforces, that is for each wire the number
+	// of positions it occupies minus one. The unused slots of a constraint reference a wire
+	// as well, and are counted.
+	NbCopyConstraints int
+}
+
+// PlonkStats returns the statistics of cs, without running a setup
+func (cs *SparseR1CS) PlonkStats() Stats {
+	nbVariables := cs.NbInternalVariables + cs.NbSecretVariables + cs.NbPublicVariables
+	nbRows := cs.NbPublicVariables + len(cs.Constraints)
+
+	// same layout as the permutation: the placeholders of the public inputs in l,
+	// then the l, r, o wires of the constraints
+	seen := make([]bool, nbVariables)
+	nbPositions, nbWires := 0, 0
+	visit := func(id int) {
+		nbPositions++
+		if !seen[id] {
+			seen[id] = true
+			nbWires++
+		}
+	}
+	for i := 0; i < cs.NbPublicVariables; i++ {
+		visit(i)
+	}
+	for i := 0; i < len(cs.Constraints); i++ {
+		visit(cs.Constraints[i].L.WireID())
+		visit(cs.Constraints[i].R.WireID())
+		visit(cs.Constraints[i].O.WireID())
+	}
+
+	return Stats{
+		NbConstraints:       len(cs.Constraints),
+		NbPublicVariables:   cs.NbPublicVariables,
+		NbSecretVariables:   cs.NbSecretVariables,
+		NbInternalVariables: cs.NbInternalVariables,
+		NbRows:              nbRows,
+		NbCopyConstraints:   nbPositions - nbWires,
+	}
+}
+
 // CurveID returns curve ID as defined in gnark-crypto (ecc.BLS24-315)
 func (cs *SparseR1CS) CurveID() ecc.ID {
 	return ecc.BLS24_315
@@ -22,7 +22,11 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr"
 
 	"bytes"
+	"encoding/hex"
+	"errors"
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/kzg"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -32,6 +36,7 @@ func TestProvingKeySerialization(t *testing.T) {
 	var vk VerifyingKey
 	vk.Size = 42
 	vk.SizeInv = fr.One()
+	vk.CosetShift.SetUint64(5)
 
 	_, _, g1gen, _ := curve.Generators()
 	vk.S[0] = g1gen
@@ -65,6 +70,8 @@ func TestProvingKeySerialization(t *testing.T) {
 	pk.Permutation = make([]int64, 3*pk.Domain[0].Cardinality)
 	pk.Permutation[0] = -12
 	pk.Permutation[len(pk.Permutation)-1] = 8888
+	pk.CircuitDigest[0] = 0xca
+	pk.CircuitDigest[31] = 0xfe
 
 	var buf bytes.Buffer
 	written, err := pk.WriteTo(&buf)
@@ -88,6 +95,91 @@ func TestProvingKeySerialization(t *testing.T) {
 	}
 }
 
+func TestProvingKeyVersion(t *testing.T) {
+	var vk VerifyingKey
+	vk.Size = 8
+	vk.SizeInv = fr.One()
+	vk.CosetShift.Set(&defaultCosetShift)
+	var pk ProvingKey
+	pk.Vk = &vk
+	pk.Domain[0] = *fft.NewDomain(8)
+	pk.Domain[1] = *fft.NewDomain(4 * 8)
+	for _, p := range []*[]fr.Element{&pk.Ql, &pk.Qr, &pk.Qm, &pk.Qo, &pk.CQk, &pk.LQk, &pk.S1Canonical, &pk.S2Canonical, &pk.S3Canonical} {
+		*p = make([]fr.Element, pk.Domain[0].Cardinality)
+	}
+	pk.Permutation = make([]int64, 3*pk.Domain[0].Cardinality)
+	pk.CircuitDigest[0] = 0xca
+
+	var buf bytes.Buffer
+	if _, err := pk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	if encoded[0] != keyVersion {
+		t.Fatalf("proving key should start with its version %d, got %d", keyVersion, encoded[0])
+	}
+
+	// version 0 has no version byte, its verifying key is of version 0 and the circuit digest
+	// is encoded last
+	var vkBuf bytes.Buffer
+	if _, err := vk.WriteTo(&vkBuf); err != nil {
+		t.Fatal(err)
+	}
+	vkSize := vkBuf.Len()
+	var legacy []byte
+	legacy = append(legacy, encoded[2:vkSize-fr.Bytes+1]...)
+	legacy = append(legacy, encoded[vkSize+1:len(encoded)-len(pk.CircuitDigest)]...)
+
+	var reconstructed ProvingKey
+	read, err := reconstructed.ReadFrom(bytes.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk.CircuitDigest = [32]byte{}
+	if read != int64(len(legacy)) || !reflect.DeepEqual(&pk, &reconstructed) {
+		t.Fatal("proving key of version 0 doesn't decode without circuit digest")
+	}
+
+	bumped := append([]byte{}, encoded...)
+	bumped[0] = keyVersion + 1
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(bumped)); !errors.Is(err, ErrUnsupportedKeyVersion) {
+		t.Fatalf("expected ErrUnsupportedKeyVersion, got %v", err)
+	}
+}
+
+// TestReadProvingKeyV0 checks that the proving key of version 0 of readProvingKeyV0 decodes to
+// the one of Setup, but for the circuit digest and the permutation.
+func TestReadProvingKeyV0(t *testing.T) {
+	_, pk, _, _ := setupTestVectorCircuit(t)
+	legacy := readProvingKeyV0(t)
+
+	if legacy.CircuitDigest != ([32]byte{}) {
+		t.Fatal("the circuit digest of a proving key of version 0 should be zero")
+	}
+	if !legacy.Vk.CosetShift.Equal(&defaultCosetShift) {
+		t.Fatal("the coset shift of a proving key of version 0 should be the default one")
+	}
+	if legacy.Vk.Size != pk.Vk.Size || legacy.Vk.NbPublicVariables != pk.Vk.NbPublicVariables ||
+		!legacy.Vk.SizeInv.Equal(&pk.Vk.SizeInv) || !legacy.Vk.Generator.Equal(&pk.Vk.Generator) {
+		t.Fatal("the verifying key of version 0 doesn't describe the domain of Setup")
+	}
+	vkDigests := []kzg.Digest{legacy.Vk.Ql, legacy.Vk.Qr, legacy.Vk.Qm, legacy.Vk.Qo, legacy.Vk.Qk}
+	for i, d := range []kzg.Digest{pk.Vk.Ql, pk.Vk.Qr, pk.Vk.Qm, pk.Vk.Qo, pk.Vk.Qk} {
+		if !vkDigests[i].Equal(&d) {
+			t.Fatalf("commitment %d of the verifying key of version 0 isn't the one of Setup", i)
+		}
+	}
+	if !reflect.DeepEqual(legacy.Domain, pk.Domain) {
+		t.Fatal("the domains of version 0 aren't the ones of Setup")
+	}
+	polynomials := [][]fr.Element{legacy.Ql, legacy.Qr, legacy.Qm, legacy.Qo, legacy.CQk, legacy.LQk}
+	for i, p := range [][]fr.Element{pk.Ql, pk.Qr, pk.Qm, pk.Qo, pk.CQk, pk.LQk} {
+		if !reflect.DeepEqual(polynomials[i], p) {
+			t.Fatalf("selector %d of version 0 isn't the one of Setup", i)
+		}
+	}
+}
+
 func TestVerifyingKeySerialization(t *testing.T) {
 	// create a random vk
 	var vk VerifyingKey
@@ -125,3 +217,215 @@ func TestVerifyingKeySerialization(t *testing.T) {
 		t.Fatal("bytes written / read don't match")
 	}
 }
+
+func TestVerifyingKeyVersion(t *testing.T) {
+	var vk VerifyingKey
+	vk.Size = 42
+	vk.SizeInv = fr.One()
+	vk.NbPublicVariables = 3
+	vk.CosetShift.Set(&defaultCosetShift)
+	_, _, g1gen, _ := curve.Generators()
+	vk.S[0] = g1gen
+	vk.Qk = g1gen
+
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	if encoded[0] != keyVersion {
+		t.Fatalf("verifying key should start with its version %d, got %d", keyVersion, encoded[0])
+	}
+
+	// version 0 has neither version byte nor coset shift, the coset shift is encoded last
+	legacy := encoded[1 : len(encoded)-fr.Bytes]
+	var reconstructed VerifyingKey
+	read, err := reconstructed.ReadFrom(bytes.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != int64(len(legacy)) || !reflect.DeepEqual(&vk, &reconstructed) {
+		t.Fatal("verifying key of version 0 doesn't decode with the coset shift of Setup")
+	}
+
+	bumped := append([]byte{}, encoded...)
+	bumped[0] = keyVersion + 1
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(bumped)); !errors.Is(err, ErrUnsupportedKeyVersion) {
+		t.Fatalf("expected ErrUnsupportedKeyVersion, got %v", err)
+	}
+}
+
+func TestProofHexMap(t *testing.T) {
+	// random proof
+	var proof Proof
+	_, _, g1gen, _ := curve.Generators()
+	for _, p := range proof.points() {
+		var s fr.Element
+		var bs big.Int
+		s.SetRandom()
+		s.ToBigIntRegular(&bs)
+		p.ScalarMultiplication(&g1gen, &bs)
+	}
+	proof.BatchedProof.ClaimedValues = make([]fr.Element, 7)
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		proof.BatchedProof.ClaimedValues[i].SetRandom()
+	}
+	proof.ZShiftedOpening.ClaimedValue.SetRandom()
+
+	m := proof.HexMap()
+
+	var reconstructed Proof
+	if err := reconstructed.SetHexMap(m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&proof, &reconstructed) {
+		t.Fatal("reconstructed proof doesn't match original")
+	}
+
+	// the modulus is not a canonical field element
+	b := fr.Modulus().Bytes()
+	m["BatchedProof.ClaimedValues[2]"] = hex.EncodeToString(b)
+	if err := reconstructed.SetHexMap(m); err == nil {
+		t.Fatal("non canonical field element should be rejected")
+	}
+
+	delete(m, "Z")
+	if err := reconstructed.SetHexMap(m); err == nil {
+		t.Fatal("missing entry should be rejected")
+	}
+}
+
+func TestProofToWitness(t *testing.T) {
+	var proof Proof
+	proof.BatchedProof.ClaimedValues = make([]fr.Element, 7)
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		proof.BatchedProof.ClaimedValues[i].SetUint64(uint64(i + 1))
+	}
+	proof.ZShiftedOpening.ClaimedValue.SetUint64(8)
+
+	w := proof.ToWitness()
+	if len(w) != 8 {
+		t.Fatalf("witness should have 8 elements, got %d", len(w))
+	}
+	for i := 0; i < len(w); i++ {
+		var expected fr.Element
+		expected.SetUint64(uint64(i + 1))
+		if !w[i].Equal(&expected) {
+			t.Fatalf("witness element %d is not at the expected position", i)
+		}
+	}
+
+	// the layout doesn't depend on the openings being present
+	if len(new(Proof).ToWitness()) != 8 {
+		t.Fatal("witness of an empty proof should have 8 elements")
+	}
+}
+
+func TestProofVersion(t *testing.T) {
+	var proof Proof
+	_, _, g1gen, _ := curve.Generators()
+	for _, p := range proof.points() {
+		p.Set(&g1gen)
+	}
+	proof.BatchedProof.ClaimedValues = make([]fr.Element, 7)
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		proof.BatchedProof.ClaimedValues[i].SetUint64(uint64(i))
+	}
+
+	var buf bytes.Buffer
+	written, err := proof.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	if encoded[0] != proofVersion {
+		t.Fatalf("proof should start with its version %d, got %d", proofVersion, encoded[0])
+	}
+
+	var reconstructed Proof
+	read, err := reconstructed.ReadFrom(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != written || !proof.Equal(&reconstructed) {
+		t.Fatal("proof doesn't survive its round trip")
+	}
+
+	bumped := append([]byte{}, encoded...)
+	bumped[0] = proofVersion + 1
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(bumped)); !errors.Is(err, ErrUnsupportedProofVersion) {
+		t.Fatalf("expected ErrUnsupportedProofVersion, got %v", err)
+	}
+}
+
+func TestProofDiff(t *testing.T) {
+	var proof Proof
+	_, _, g1gen, _ := curve.Generators()
+	for _, p := range proof.points() {
+		var s fr.Element
+		var bs big.Int
+		s.SetRandom()
+		s.ToBigIntRegular(&bs)
+		p.ScalarMultiplication(&g1gen, &bs)
+	}
+	proof.BatchedProof.ClaimedValues = make([]fr.Element, 7)
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		proof.BatchedProof.ClaimedValues[i].SetRandom()
+	}
+	proof.ZShiftedOpening.ClaimedValue.SetRandom()
+
+	if len(proofPointNames) != len(proof.points()) {
+		t.Fatal("proofPointNames doesn't cover all the points of the proof")
+	}
+
+	clone := func() *Proof {
+		var b bytes.Buffer
+		if _, err := proof.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		var res Proof
+		if _, err := res.ReadFrom(&b); err != nil {
+			t.Fatal(err)
+		}
+		return &res
+	}
+
+	other := clone()
+	if !proof.Equal(other) || proof.Diff(other) != "" {
+		t.Fatal("a proof should be equal to its round trip")
+	}
+
+	for _, name := range proofPointNames {
+		other := clone()
+		other.points()[name].Neg(other.points()[name])
+		if d := proof.Diff(other); d != name {
+			t.Fatalf("expected diff %s, got %q", name, d)
+		}
+	}
+
+	other = clone()
+	other.BatchedProof.ClaimedValues[3].SetOne()
+	if d := proof.Diff(other); d != "BatchedProof.ClaimedValues[3]" {
+		t.Fatalf("unexpected diff %q", d)
+	}
+
+	other = clone()
+	other.BatchedProof.ClaimedValues = other.BatchedProof.ClaimedValues[:6]
+	if d := proof.Diff(other); d != "len(BatchedProof.ClaimedValues)" {
+		t.Fatalf("unexpected diff %q", d)
+	}
+
+	other = clone()
+	other.ZShiftedOpening.ClaimedValue.SetOne()
+	if d := proof.Diff(other); d != "ZShiftedOpening.ClaimedValue" {
+		t.Fatalf("unexpected diff %q", d)
+	}
+
+	// the first differing field is reported
+	other = clone()
+	other.ZShiftedOpening.ClaimedValue.SetOne()
+	other.H[1].Neg(&other.H[1])
+	if proof.Equal(other) || proof.Diff(other) != "H[1]" {
+		t.Fatal("the first differing field should be reported")
+	}
+}
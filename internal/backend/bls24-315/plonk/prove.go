@@ -18,6 +18,10 @@ package plonk
 
 import (
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"math/big"
 	"math/bits"
 	"runtime"
@@ -36,12 +40,46 @@ import (
 
 	"github.com/consensys/gnark/internal/backend/bls24-315/cs"
 
-	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/debug"
 	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 )
 
+var (
+	errMissingCosetTable           = errors.New("big domain is missing its coset tables, it must be created with fft.NewDomain")
+	errInsufficientBlinding        = errors.New("blinding order is too small for the number of openings")
+	errInvalidDomainRatio          = errors.New("big domain cardinality must be a multiple of the small domain cardinality")
+	errQuotientTooLarge            = errors.New("big domain is too small to hold the split quotient")
+	errQuotientSplit               = errors.New("h1, h2, h3 don't recombine to the quotient")
+	errInvalidBigDomainEvaluations = errors.New("evaluations on the big domain don't match the big domain size")
+	errBlindingCapacity            = errors.New("polynomial to blind doesn't have the capacity to hold the blinded polynomial")
+	errBlindingDegree              = errors.New("polynomial to blind has a too high degree")
+	errDegenerateCoset             = errors.New("big domain coset intersects the small domain, Xⁿ-1 vanishes on it")
+
+	// ErrZeroDenominator is returned when the challenges β, γ cancel a term of the denominator of Z,
+	// which can happen with unlucky or adversarial challenges. The remedy is to re-derive the
+	// challenges, for instance by proving again with a fresh blinding.
+	ErrZeroDenominator = errors.New("zero denominator in the computation of Z")
+
+	// ErrDomainTooLarge is returned when the big domain can't be indexed by an int on the
+	// platform (for instance a domain of size 2³¹ or more on a 32-bit platform).
+	ErrDomainTooLarge = errors.New("fft domain is too large for the platform")
+)
+
+// Ordering conventions of the prover:
+// * the Lagrange forms on the small domain (the solution, l, r, o, z before interpolation) are
+// in natural order: the i-th entry is the value at ωⁱ;
+// * the canonical forms are in natural order: the i-th entry is the coefficient of Xⁱ;
+// * the evaluations on the big domain (coset) are in bit reversed order, as output by a DIF fft
+// of a canonical form (evaluateDomainBigBitReversed), and are combined entry-wise;
+// * the quotient is interpolated back to canonical form with a DIT fft, which takes its input
+// in bit reversed order and outputs it in natural order.
+// The interpolations on the small domain go from natural order to natural order: copying the
+// Lagrange form in bit reversed order (copyBitReversed) before a DIT fft avoids a separate
+// fft.BitReverse pass. z is computed in place by a running product, so it keeps the pass.
+
 type Proof struct {
 
 	// Commitments to the solution vectors
@@ -58,21 +96,27 @@ type Proof struct {
 
 	// Opening proof of Z at zeta*mu
 	ZShiftedOpening kzg.OpeningProof
+
+	// Nonce bound to the transcript before any other data, see backend.WithNonce. Verify doesn't
+	// compare it with an expected value, this is left to the caller.
+	Nonce []byte
 }
 
 // Prove from the public data
+//
+// Prove can be called concurrently with the same pk, including after FreeScratch or ReadFrom.
+// pk must not be modified meanwhile (FreeScratch, SetBigDomainCosetShift, GrowDomain, InitKZG).
 func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witness, opt backend.ProverConfig) (*Proof, error) {
+	deadline := proverDeadline(opt)
 
-	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "plonk").Logger()
-	start := time.Now()
-	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
-
-	// create a transcript manager to apply Fiat Shamir
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
-
-	// result
-	proof := &Proof{}
+	// the solver works with the coefficients of spr, they must be elements of fr
+	if err := checkScalarField(spr); err != nil {
+		return nil, err
+	}
+	// pk must have been set up for spr
+	if err := checkCircuitDigest(spr, pk); err != nil {
+		return nil, err
+	}
 
 	// compute the constraint system solution
 	var solution []fr.Element
@@ -90,6 +134,140 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 			}
 		}
 	}
+	if opt.Solution != nil {
+		*opt.Solution = toBigInts(solution)
+	}
+	if err := checkDeadline(deadline); err != nil {
+		return nil, err
+	}
+	reportProgress(opt, "solve", 0.1)
+
+	return prove(spr, pk, solution, opt, deadline)
+}
+
+// ProveWithSolution is like Prove, but skips solving the constraint system and uses
+// solution instead. solution must be ordered as returned by spr.Solve:
+// [ public | secret | internal ].
+//
+// The solution is not checked against the constraints: the caller is responsible for
+// its correctness, otherwise the resulting proof will not verify.
+func ProveWithSolution(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.Element, opt backend.ProverConfig) (*Proof, error) {
+	deadline := proverDeadline(opt)
+	if err := checkScalarField(spr); err != nil {
+		return nil, err
+	}
+	if err := checkCircuitDigest(spr, pk); err != nil {
+		return nil, err
+	}
+	nbVariables := spr.NbInternalVariables + spr.NbSecretVariables + spr.NbPublicVariables
+	if len(solution) != nbVariables {
+		return nil, fmt.Errorf(
+			"invalid solution size, got %d, expected %d = %d (public) + %d (secret) + %d (internal)",
+			len(solution),
+			nbVariables,
+			spr.NbPublicVariables,
+			spr.NbSecretVariables,
+			spr.NbInternalVariables,
+		)
+	}
+	return prove(spr, pk, solution, opt, deadline)
+}
+
+// proverDeadline returns the deadline of the prover set with backend.WithTimeout, or the zero
+// time if there is none
+func proverDeadline(opt backend.ProverConfig) time.Time {
+	if opt.Timeout <= 0 {
+		return time.Time{}
+	}
+	return clock().Add(opt.Timeout)
+}
+
+// clock returns the current time for the deadline of the prover, the tests replace it with a
+// fake clock
+var clock = time.Now
+
+// toBigInts returns the coefficients of p as big integers, in regular form
+func toBigInts(p []fr.Element) []big.Int {
+	res := make([]big.Int, len(p))
+	for i := 0; i < len(p); i++ {
+		p[i].ToBigIntRegular(&res[i])
+	}
+	return res
+}
+
+// reportProgress calls the progress callback of opt if any, see backend.WithProgress
+func reportProgress(opt backend.ProverConfig, phase string, fraction float64) {
+	if opt.Progress != nil {
+		opt.Progress(phase, fraction)
+	}
+}
+
+// checkDeadline returns backend.ErrProveTimeout if deadline is set and passed
+func checkDeadline(deadline time.Time) error {
+	if !deadline.IsZero() && clock().After(deadline) {
+		return backend.ErrProveTimeout
+	}
+	return nil
+}
+
+// prove computes the proof from the solution of the constraint system.
+//
+// The deadline is checked between the steps of the prover, once the worker goroutines of the
+// previous step are done.
+func prove(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.Element, opt backend.ProverConfig, deadline time.Time) (*Proof, error) {
+
+	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "plonk").Logger()
+	start := time.Now()
+	if opt.AuditTrail != nil {
+		log.Warn().Msg("prover audit trail is set: it leaks the witness, the proof is not zero-knowledge to its readers")
+	}
+	// pick a hash function that will be used to batch the openings
+	hFunc := sha256.New()
+
+	// create a transcript manager to apply Fiat Shamir, bound to the public data.
+	// The first challenge is derived using the public data: the commitments to the permutation,
+	// the coefficients of the circuit, and the public inputs.
+	if len(opt.Nonce) > maxNonceSize {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d", errNonceTooLarge, len(opt.Nonce), maxNonceSize)
+	}
+	fs, err := newTranscript(pk.Vk, solution[:spr.NbPublicVariables], opt.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if opt.PublicWitness != nil {
+		*opt.PublicWitness = make([]big.Int, spr.NbPublicVariables)
+		for i := 0; i < spr.NbPublicVariables; i++ {
+			solution[i].ToBigIntRegular(&(*opt.PublicWitness)[i])
+		}
+	}
+
+	// result
+	proof := &Proof{}
+	if len(opt.Nonce) != 0 {
+		proof.Nonce = append([]byte{}, opt.Nonce...)
+	}
+
+	if err := checkDomainRatio(pk); err != nil {
+		return nil, err
+	}
+
+	// the prover indexes the domains with ints: the conversions int(pk.Domain[i].Cardinality)
+	// below are safe once the big domain, which is the largest, is checked
+	if err := checkDomainCardinality(pk.Domain[1].Cardinality, math.MaxInt); err != nil {
+		return nil, err
+	}
+
+	// all the evaluations on the big domain are done on a coset, using the precomputed coset tables
+	if err := checkCosetTables(&pk.Domain[1]); err != nil {
+		return nil, err
+	}
+
+	// the evaluations of the permutation, the selectors and qk on the big domain are not
+	// serialized and may have been released by FreeScratch
+	pk.restoreBigDomainEvaluations()
+	if err := checkBigDomainEvaluations(pk); err != nil {
+		return nil, err
+	}
 
 	// query l, r, o in Lagrange basis, not blinded
 	evaluationLDomainSmall, evaluationRDomainSmall, evaluationODomainSmall := evaluateLROSmallDomain(spr, pk, solution)
@@ -109,13 +287,12 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 	if err := commitToLRO(blindedLCanonical, blindedRCanonical, blindedOCanonical, proof, pk.Vk.KZGSRS); err != nil {
 		return nil, err
 	}
-
-	// The first challenge is derived using the public data: the commitments to the permutation,
-	// the coefficients of the circuit, and the public inputs.
-	// derive gamma from the Comm(blinded cl), Comm(blinded cr), Comm(blinded co)
-	if err := bindPublicData(&fs, "gamma", *pk.Vk, fullWitness[:spr.NbPublicVariables]); err != nil {
+	if err := checkDeadline(deadline); err != nil {
 		return nil, err
 	}
+	reportProgress(opt, "commit-lro", 0.25)
+
+	// derive gamma from the public data
 	bgamma, err := fs.ComputeChallenge("gamma")
 	if err != nil {
 		return nil, err
@@ -124,10 +301,15 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 	gamma.SetBytes(bgamma)
 
 	// Fiat Shamir this
-	beta, err := deriveRandomness(&fs, "beta")
+	beta, err := deriveRandomness(fs, "beta")
 	if err != nil {
 		return nil, err
 	}
+	if opt.Challenges != nil {
+		// INSECURE, test only, see backend.WithInsecureFixedChallenges
+		gamma.SetBigInt(&opt.Challenges.Gamma)
+		beta.SetBigInt(&opt.Challenges.Beta)
+	}
 
 	// compute Z, the permutation accumulator polynomial, in canonical basis
 	// ll, lr, lo are NOT blinded
@@ -159,7 +341,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 		}
 
 		// derive alpha from the Comm(l), Comm(r), Comm(o), Com(Z)
-		alpha, err = deriveRandomness(&fs, "alpha", &proof.Z)
+		alpha, err = deriveRandomness(fs, "alpha", &proof.Z)
+		if opt.Challenges != nil {
+			alpha.SetBigInt(&opt.Challenges.Alpha)
+		}
 		chZ <- err
 		close(chZ)
 	}()
@@ -188,15 +373,17 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 		close(chEvalBO)
 	}()
 
+	// L₁ on the coset of the big domain, used to complete qk and for the startsAtOne constraint
+	evaluationLOneDomainBigBitReversed := evaluateLOneDomainBigBitReversed(pk)
+
 	var constraintsInd, constraintsOrdering []fr.Element
 	chConstraintInd := make(chan struct{}, 1)
 	go func() {
-		// compute qk in canonical basis, completed with the public inputs
-		qkCompletedCanonical := make([]fr.Element, pk.Domain[0].Cardinality)
-		copy(qkCompletedCanonical, fullWitness[:spr.NbPublicVariables])
-		copy(qkCompletedCanonical[spr.NbPublicVariables:], pk.LQk[spr.NbPublicVariables:])
-		pk.Domain[0].FFTInverse(qkCompletedCanonical, fft.DIF)
-		fft.BitReverse(qkCompletedCanonical)
+		// evaluate qk on the coset of the big domain, completed with the public inputs
+		evaluationQkCompleteDomainBigBitReversed := evaluateQkCompleteDomainBigBitReversed(
+			pk,
+			evaluationLOneDomainBigBitReversed,
+			solution[:spr.NbPublicVariables])
 
 		// compute the evaluation of qlL+qrR+qmL.R+qoO+k on the coset of the big domain
 		// → uses the blinded version of l, r, o
@@ -208,7 +395,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 			evaluationBlindedLDomainBigBitReversed,
 			evaluationBlindedRDomainBigBitReversed,
 			evaluationBlindedODomainBigBitReversed,
-			qkCompletedCanonical)
+			evaluationQkCompleteDomainBigBitReversed)
 		close(chConstraintInd)
 	}()
 
@@ -238,24 +425,138 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 	}()
 
 	if err := <-chConstraintOrdering; err != nil {
+		// z is not evaluated on the big domain, wait for the evaluation of the constraints which
+		// reads l, r, o before releasing them
+		<-chConstraintInd
+		putBigDomainBuffers(
+			evaluationBlindedLDomainBigBitReversed,
+			evaluationBlindedRDomainBigBitReversed,
+			evaluationBlindedODomainBigBitReversed,
+			evaluationLOneDomainBigBitReversed,
+			constraintsInd,
+		)
 		return nil, err
 	}
 
 	<-chConstraintInd
+	if err := checkDeadline(deadline); err != nil {
+		putBigDomainBuffers(
+			evaluationBlindedLDomainBigBitReversed,
+			evaluationBlindedRDomainBigBitReversed,
+			evaluationBlindedODomainBigBitReversed,
+			evaluationBlindedZDomainBigBitReversed,
+			evaluationLOneDomainBigBitReversed,
+			constraintsInd,
+			constraintsOrdering,
+		)
+		return nil, err
+	}
+
+	// compute L₁(X)*(Z(X)-1) on the coset of the big domain
+	startsAtOne := evaluateStartsAtOneDomainBigBitReversed(evaluationLOneDomainBigBitReversed, evaluationBlindedZDomainBigBitReversed)
+
+	// compute h in canonical form, and split it as h1+Xᵐh2+X²ᵐh3
+	h, err := computeQuotientCanonical(pk, [][]fr.Element{constraintsInd, constraintsOrdering, startsAtOne}, alpha)
+	if err != nil {
+		putBigDomainBuffers(
+			evaluationBlindedLDomainBigBitReversed,
+			evaluationBlindedRDomainBigBitReversed,
+			evaluationBlindedODomainBigBitReversed,
+			evaluationBlindedZDomainBigBitReversed,
+			evaluationLOneDomainBigBitReversed,
+			constraintsInd,
+			constraintsOrdering,
+			startsAtOne,
+		)
+		return nil, err
+	}
+	defer putBigDomainBuffers(h)
+	reportProgress(opt, "quotient", 0.6)
+
+	// all the evaluations on the big domain are alive, this is the peak of the memory usage
+	if opt.MemStats != nil {
+		opt.MemStats.BigDomainBytes = bigDomainBytes(
+			evaluationBlindedLDomainBigBitReversed,
+			evaluationBlindedRDomainBigBitReversed,
+			evaluationBlindedODomainBigBitReversed,
+			evaluationBlindedZDomainBigBitReversed,
+			evaluationLOneDomainBigBitReversed,
+			constraintsInd,
+			constraintsOrdering,
+			startsAtOne,
+			h,
+		)
+		opt.MemStats.ProvingKeyBigDomainBytes = bigDomainBytes(
+			pk.EvaluationPermutationBigDomainBitReversed,
+			pk.EvaluationSelectorsDomainBigBitReversed,
+			pk.EvaluationQkIncompleteDomainBigBitReversed,
+		)
+	}
 
-	// compute h in canonical form
-	h1, h2, h3 := computeQuotientCanonical(pk, constraintsInd, constraintsOrdering, evaluationBlindedZDomainBigBitReversed, alpha)
+	// h is modified in place when folded, output a copy
+	if opt.Diagnostics != nil {
+		opt.Diagnostics.Quotient = toBigInts(h)
+	}
+
+	// the evaluations on the big domain are not needed anymore
+	putBigDomainBuffers(
+		evaluationBlindedLDomainBigBitReversed,
+		evaluationBlindedRDomainBigBitReversed,
+		evaluationBlindedODomainBigBitReversed,
+		evaluationBlindedZDomainBigBitReversed,
+		evaluationLOneDomainBigBitReversed,
+		constraintsInd,
+		constraintsOrdering,
+		startsAtOne,
+	)
+	boLRO, boZ := blindingOrder(nbOpeningsLRO), blindingOrder(nbOpeningsZ)
+	h1, h2, h3, err := splitQuotient(h, pk.Domain[0].Cardinality, boLRO, boZ)
+	if err != nil {
+		return nil, err
+	}
+
+	// in debug builds, ensure no coefficient of h is lost or misplaced by the split.
+	// When forcing the proof of an invalid witness, h is not a polynomial of the expected degree.
+	if debug.Debug && !opt.Force {
+		if err := checkQuotientSplit(h, h1, h2, h3, quotientSplitSize(pk.Domain[0].Cardinality, boLRO, boZ)); err != nil {
+			return nil, err
+		}
+	}
 
 	// compute kzg commitments of h1, h2 and h3
 	if err := commitToQuotient(h1, h2, h3, proof, pk.Vk.KZGSRS); err != nil {
 		return nil, err
 	}
 
+	// h3 is folded in place and the memory of h is recycled by the next proofs, output copies
+	// of the committed polynomials
+	if opt.AuditTrail != nil {
+		opt.AuditTrail.L = toBigInts(blindedLCanonical)
+		opt.AuditTrail.R = toBigInts(blindedRCanonical)
+		opt.AuditTrail.O = toBigInts(blindedOCanonical)
+		opt.AuditTrail.Z = toBigInts(blindedZCanonical)
+		opt.AuditTrail.H = [3][]big.Int{toBigInts(h1), toBigInts(h2), toBigInts(h3)}
+	}
+	if err := checkDeadline(deadline); err != nil {
+		return nil, err
+	}
+	reportProgress(opt, "commit-quotient", 0.75)
+
+	if opt.CommitOnly {
+		log.Debug().Dur("took", time.Since(start)).Msg("prover done (commitments only)")
+		reportProgress(opt, "done", 1)
+		return proof, nil
+	}
+
 	// derive zeta
-	zeta, err := deriveRandomness(&fs, "zeta", &proof.H[0], &proof.H[1], &proof.H[2])
+	zeta, err := deriveRandomness(fs, "zeta", &proof.H[0], &proof.H[1], &proof.H[2])
 	if err != nil {
 		return nil, err
 	}
+	if opt.Challenges != nil {
+		zeta.SetBigInt(&opt.Challenges.Zeta)
+	}
+	zeta = rederiveZeta(zeta, new(big.Int).SetUint64(pk.Domain[0].Cardinality))
 
 	// compute evaluations of (blinded version of) l, r, o, z at zeta
 	var blzeta, brzeta, bozeta fr.Element
@@ -320,7 +621,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 
 	// foldedHDigest = Comm(h1) + ζᵐ⁺²*Comm(h2) + ζ²⁽ᵐ⁺²⁾*Comm(h3)
 	var bZetaPowerm, bSize big.Int
-	bSize.SetUint64(pk.Domain[0].Cardinality + 2) // +2 because of the masking (h of degree 3(n+2)-1)
+	bSize.SetUint64(quotientSplitSize(pk.Domain[0].Cardinality, boLRO, boZ))
 	var zetaPowerm fr.Element
 	zetaPowerm.Exp(zeta, &bSize)
 	zetaPowerm.ToBigIntRegular(&bZetaPowerm)
@@ -345,6 +646,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 	if errLPoly != nil {
 		return nil, errLPoly
 	}
+	if err := checkDeadline(deadline); err != nil {
+		return nil, err
+	}
+	reportProgress(opt, "linearize", 0.9)
 
 	// Batch open the first list of polynomials
 	proof.BatchedProof, err = kzg.BatchOpenSinglePoint(
@@ -376,6 +681,7 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls24_315witness.Witn
 	if err != nil {
 		return nil, err
 	}
+	reportProgress(opt, "done", 1)
 
 	return proof, nil
 
@@ -390,9 +696,55 @@ func eval(c []fr.Element, p fr.Element) fr.Element {
 	return r
 }
 
+// CommitStreaming returns the kzg commitment of the polynomial, in canonical form, whose
+// consecutive chunks of coefficients are returned by next. next returns io.EOF after the last
+// chunk.
+//
+// The commitment is linear in the coefficients: it is the sum of the commitments of the chunks,
+// each against the points of srs at its offset. Only one chunk needs to be in memory at a time,
+// and the result is the one of kzg.Commit on the whole polynomial.
+func CommitStreaming(srs *kzg.SRS, next func() ([]fr.Element, error)) (kzg.Digest, error) {
+	var res curve.G1Jac
+	offset := 0
+	for {
+		chunk, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return kzg.Digest{}, err
+		}
+		if offset+len(chunk) > len(srs.G1) {
+			return kzg.Digest{}, kzg.ErrInvalidPolynomialSize
+		}
+		var c curve.G1Jac
+		if _, err := c.MultiExp(srs.G1[offset:offset+len(chunk)], chunk, ecc.MultiExpConfig{ScalarsMont: true}); err != nil {
+			return kzg.Digest{}, err
+		}
+		res.AddAssign(&c)
+		offset += len(chunk)
+	}
+	if offset == 0 {
+		return kzg.Digest{}, kzg.ErrInvalidPolynomialSize
+	}
+	var digest kzg.Digest
+	digest.FromJacobian(&res)
+	return digest, nil
+}
+
+// nbTasksPerCommitment returns the number of tasks of each of the 3 commitments computed
+// concurrently by commitToLRO and commitToQuotient, half of the CPUs, and at least 1 on a
+// single core machine.
+func nbTasksPerCommitment(numCPU int) int {
+	if numCPU < 2 {
+		return 1
+	}
+	return numCPU / 2
+}
+
 // fills proof.LRO with kzg commits of bcl, bcr and bco
 func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS) error {
-	n := runtime.NumCPU() / 2
+	n := nbTasksPerCommitment(runtime.NumCPU())
 	var err0, err1, err2 error
 	chCommit0 := make(chan struct{}, 1)
 	chCommit1 := make(chan struct{}, 1)
@@ -404,12 +756,15 @@ func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS) error {
 		proof.LRO[1], err1 = kzg.Commit(bcr, srs, n)
 		close(chCommit1)
 	}()
-	if proof.LRO[2], err2 = kzg.Commit(bco, srs, n); err2 != nil {
-		return err2
-	}
+	proof.LRO[2], err2 = kzg.Commit(bco, srs, n)
+
+	// wait for both goroutines even on error, they write to proof
 	<-chCommit0
 	<-chCommit1
 
+	if err2 != nil {
+		return err2
+	}
 	if err0 != nil {
 		return err0
 	}
@@ -418,7 +773,7 @@ func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *kzg.SRS) error {
 }
 
 func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error {
-	n := runtime.NumCPU() / 2
+	n := nbTasksPerCommitment(runtime.NumCPU())
 	var err0, err1, err2 error
 	chCommit0 := make(chan struct{}, 1)
 	chCommit1 := make(chan struct{}, 1)
@@ -430,12 +785,15 @@ func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error
 		proof.H[1], err1 = kzg.Commit(h2, srs, n)
 		close(chCommit1)
 	}()
-	if proof.H[2], err2 = kzg.Commit(h3, srs, n); err2 != nil {
-		return err2
-	}
+	proof.H[2], err2 = kzg.Commit(h3, srs, n)
+
+	// wait for both goroutines even on error, they write to proof
 	<-chCommit0
 	<-chCommit1
 
+	if err2 != nil {
+		return err2
+	}
 	if err0 != nil {
 		return err0
 	}
@@ -446,33 +804,32 @@ func commitToQuotient(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error
 // computeBlindedLROCanonical l, r, o in canonical basis with blinding
 func computeBlindedLROCanonical(ll, lr, lo []fr.Element, domain *fft.Domain) (bcl, bcr, bco []fr.Element, err error) {
 
+	bo := blindingOrder(nbOpeningsLRO)
+
 	// note that bcl, bcr and bco reuses cl, cr and co memory
-	cl := make([]fr.Element, domain.Cardinality, domain.Cardinality+2)
-	cr := make([]fr.Element, domain.Cardinality, domain.Cardinality+2)
-	co := make([]fr.Element, domain.Cardinality, domain.Cardinality+2)
+	cl := make([]fr.Element, domain.Cardinality, domain.Cardinality+bo+1)
+	cr := make([]fr.Element, domain.Cardinality, domain.Cardinality+bo+1)
+	co := make([]fr.Element, domain.Cardinality, domain.Cardinality+bo+1)
 
 	chDone := make(chan error, 2)
 
 	go func() {
 		var err error
-		copy(cl, ll)
-		domain.FFTInverse(cl, fft.DIF)
-		fft.BitReverse(cl)
-		bcl, err = blindPoly(cl, domain.Cardinality, 1)
+		copyBitReversed(cl, ll)
+		domain.FFTInverse(cl, fft.DIT)
+		bcl, err = blindPoly(cl, domain.Cardinality, bo, nbOpeningsLRO)
 		chDone <- err
 	}()
 	go func() {
 		var err error
-		copy(cr, lr)
-		domain.FFTInverse(cr, fft.DIF)
-		fft.BitReverse(cr)
-		bcr, err = blindPoly(cr, domain.Cardinality, 1)
+		copyBitReversed(cr, lr)
+		domain.FFTInverse(cr, fft.DIT)
+		bcr, err = blindPoly(cr, domain.Cardinality, bo, nbOpeningsLRO)
 		chDone <- err
 	}()
-	copy(co, lo)
-	domain.FFTInverse(co, fft.DIF)
-	fft.BitReverse(co)
-	if bco, err = blindPoly(co, domain.Cardinality, 1); err != nil {
+	copyBitReversed(co, lo)
+	domain.FFTInverse(co, fft.DIT)
+	if bco, err = blindPoly(co, domain.Cardinality, bo, nbOpeningsLRO); err != nil {
 		return
 	}
 	err = <-chDone
@@ -484,20 +841,49 @@ func computeBlindedLROCanonical(ll, lr, lo []fr.Element, domain *fft.Domain) (bc
 
 }
 
+const (
+	nbOpeningsLRO = 1 // l, r, o are opened at ζ
+	nbOpeningsZ   = 2 // z is opened at ζ and μζ
+)
+
+// blindingOrder returns the minimal blinding order of a polynomial opened at nbOpenings points.
+//
+// Each opening reveals an evaluation of the blinded polynomial, so the blinding factor Q(X)*(Xⁿ-1)
+// must have strictly more random coefficients than there are openings, that is deg Q ⩾ nbOpenings.
+func blindingOrder(nbOpenings int) uint64 {
+	return uint64(nbOpenings)
+}
+
 // blindPoly blinds a polynomial by adding a Q(X)*(X**degree-1), where deg Q = order.
 //
 // * cp polynomial in canonical form
 // * rou root of unity, meaning the blinding factor is multiple of X**rou-1
 // * bo blinding order,  it's the degree of Q, where the blinding is Q(X)*(X**degree-1)
+// * nbOpenings number of points at which the blinded polynomial is opened
 //
-// WARNING:
-// pre condition degree(cp) ⩽ rou + bo
-// pre condition cap(cp) ⩾ int(totalDegree + 1)
-func blindPoly(cp []fr.Element, rou, bo uint64) ([]fr.Element, error) {
+// It returns an error if the pre conditions don't hold:
+// bo ⩾ blindingOrder(nbOpenings)
+// degree(cp) ⩽ rou + bo
+// cap(cp) ⩾ int(totalDegree + 1)
+func blindPoly(cp []fr.Element, rou, bo uint64, nbOpenings int) ([]fr.Element, error) {
+
+	// fewer random coefficients than openings would leak information about the witness
+	if bo < blindingOrder(nbOpenings) {
+		return nil, fmt.Errorf("%w: blinding order %d for %d opening(s)", errInsufficientBlinding, bo, nbOpenings)
+	}
 
 	// degree of the blinded polynomial is max(rou+order, cp.Degree)
 	totalDegree := rou + bo
 
+	if uint64(cap(cp)) < totalDegree+1 {
+		return nil, fmt.Errorf("%w: capacity %d, blinded polynomial of degree %d", errBlindingCapacity, cap(cp), totalDegree)
+	}
+	for i := totalDegree + 1; i < uint64(len(cp)); i++ {
+		if !cp[i].IsZero() {
+			return nil, fmt.Errorf("%w: coefficient %d is not zero, degree must be at most %d", errBlindingDegree, i, totalDegree)
+		}
+	}
+
 	// re-use cp
 	res := cp[:totalDegree+1]
 
@@ -529,12 +915,11 @@ func evaluateLROSmallDomain(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.El
 	l = make([]fr.Element, s)
 	r = make([]fr.Element, s)
 	o = make([]fr.Element, s)
-	s0 := solution[0]
-
+	// the entries of l, r, o which are not the wire of a constraint (the right and output
+	// entries of the placeholders, and the padding) are fixed points of the permutation,
+	// so they are left to zero rather than set to a wire of the solution.
 	for i := 0; i < spr.NbPublicVariables; i++ { // placeholders
 		l[i] = solution[i]
-		r[i] = s0
-		o[i] = s0
 	}
 	offset := spr.NbPublicVariables
 	for i := 0; i < len(spr.Constraints); i++ { // constraints
@@ -544,16 +929,38 @@ func evaluateLROSmallDomain(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.El
 	}
 	offset += len(spr.Constraints)
 
-	for i := 0; i < s-offset; i++ { // offset to reach 2**n constraints (where the id of l,r,o is 0, so we assign solution[0])
-		l[offset+i] = s0
-		r[offset+i] = s0
-		o[offset+i] = s0
+	// the proving keys of version 0 put these entries in the cycle of wire 0 instead of
+	// leaving them as fixed points, they must then be set to solution[0].
+	s0 := solution[0]
+	for i := 0; i < s; i++ {
+		if i >= offset && pk.Permutation[i] != int64(i) {
+			l[i] = s0
+		}
+		if i >= spr.NbPublicVariables && i < offset {
+			continue
+		}
+		if pk.Permutation[s+i] != int64(s+i) {
+			r[i] = s0
+		}
+		if pk.Permutation[2*s+i] != int64(2*s+i) {
+			o[i] = s0
+		}
 	}
 
 	return l, r, o
 
 }
 
+// copyBitReversed copies src to dst in bit reversed order: dst[bitReverse(i)] = src[i], where
+// the bit reversal is the one of the indices of dst. len(dst) must be a power of 2, at least
+// len(src); the entries of dst which don't receive a value of src are left untouched.
+func copyBitReversed(dst, src []fr.Element) {
+	nn := uint64(64 - bits.TrailingZeros64(uint64(len(dst))))
+	for i := 0; i < len(src); i++ {
+		dst[bits.Reverse64(uint64(i))>>nn] = src[i]
+	}
+}
+
 // computeZ computes Z, in canonical basis, where:
 //
 // * Z of degree n (domainNum.Cardinality)
@@ -565,8 +972,10 @@ func evaluateLROSmallDomain(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.El
 //	* l, r, o are the solution in Lagrange basis, evaluated on the small domain
 func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma fr.Element) ([]fr.Element, error) {
 
+	bo := blindingOrder(nbOpeningsZ)
+
 	// note that z has more capacity has its memory is reused for blinded z later on
-	z := make([]fr.Element, pk.Domain[0].Cardinality, pk.Domain[0].Cardinality+3)
+	z := make([]fr.Element, pk.Domain[0].Cardinality, pk.Domain[0].Cardinality+bo+1)
 	nbElmts := int(pk.Domain[0].Cardinality)
 	gInv := make([]fr.Element, pk.Domain[0].Cardinality)
 
@@ -598,6 +1007,12 @@ func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma
 		}
 	})
 
+	// fr.BatchInvert doesn't fail on zero entries, it would silently produce a wrong Z
+	for i := 1; i < nbElmts; i++ {
+		if gInv[i].IsZero() {
+			return nil, fmt.Errorf("%w: at row %d", ErrZeroDenominator, i-1)
+		}
+	}
 	gInv = fr.BatchInvert(gInv)
 	for i := 1; i < nbElmts; i++ {
 		z[i].Mul(&z[i], &z[i-1]).
@@ -607,7 +1022,7 @@ func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma
 	pk.Domain[0].FFTInverse(z, fft.DIF)
 	fft.BitReverse(z)
 
-	return blindPoly(z, pk.Domain[0].Cardinality, 2)
+	return blindPoly(z, pk.Domain[0].Cardinality, bo, nbOpeningsZ)
 
 }
 
@@ -615,44 +1030,45 @@ func computeBlindedZCanonical(l, r, o []fr.Element, pk *ProvingKey, beta, gamma
 // the big domain coset.
 //
 // * evalL, evalR, evalO are the evaluation of the blinded solution vectors on odd cosets
-// * qk is the completed version of qk, in canonical version
-func evaluateConstraintsDomainBigBitReversed(pk *ProvingKey, evalL, evalR, evalO, qk []fr.Element) []fr.Element {
-	var evalQl, evalQr, evalQm, evalQo, evalQk []fr.Element
-	var wg sync.WaitGroup
-	wg.Add(4)
-
-	go func() {
-		evalQl = evaluateDomainBigBitReversed(pk.Ql, &pk.Domain[1])
-		wg.Done()
-	}()
-	go func() {
-		evalQr = evaluateDomainBigBitReversed(pk.Qr, &pk.Domain[1])
-		wg.Done()
-	}()
-	go func() {
-		evalQm = evaluateDomainBigBitReversed(pk.Qm, &pk.Domain[1])
-		wg.Done()
-	}()
-	go func() {
-		evalQo = evaluateDomainBigBitReversed(pk.Qo, &pk.Domain[1])
-		wg.Done()
-	}()
-	evalQk = evaluateDomainBigBitReversed(qk, &pk.Domain[1])
-	wg.Wait()
+// * evalQk is the evaluation of the completed version of qk on odd cosets, it is overwritten by the result
+func evaluateConstraintsDomainBigBitReversed(pk *ProvingKey, evalL, evalR, evalO, evalQk []fr.Element) []fr.Element {
+	// the terms of the selectors which are identically zero (for instance qm in a circuit
+	// without multiplication gates, see pk.ZeroSelectors) are skipped: their evaluation is left nil.
+	// Note that a selector vanishing on some rows of the small domain doesn't vanish on the
+	// coset, only the identically zero ones can be skipped.
+	n := pk.Domain[1].Cardinality
+	var evals [4][]fr.Element
+	for i := range evals {
+		if !pk.ZeroSelectors[i] {
+			evals[i] = pk.EvaluationSelectorsDomainBigBitReversed[uint64(i)*n : uint64(i+1)*n]
+		}
+	}
+	evalQl, evalQr, evalQm, evalQo := evals[0], evals[1], evals[2], evals[3]
 
 	// computes the evaluation of qrR+qlL+qmL.R+qoO+k on the coset of the big domain
 	utils.Parallelize(len(evalQk), func(start, end int) {
 		var t0, t1 fr.Element
 		for i := start; i < end; i++ {
-			t1.Mul(&evalQm[i], &evalR[i]) // qm.r
-			t1.Add(&t1, &evalQl[i])       // qm.r + ql
-			t1.Mul(&t1, &evalL[i])        //  qm.l.r + ql.l
+			t0.SetZero()
+			if evalQm != nil {
+				t1.Mul(&evalQm[i], &evalR[i]) // qm.r
+				if evalQl != nil {
+					t1.Add(&t1, &evalQl[i]) // qm.r + ql
+				}
+				t0.Mul(&t1, &evalL[i]) //  qm.l.r + ql.l
+			} else if evalQl != nil {
+				t0.Mul(&evalQl[i], &evalL[i]) // ql.l
+			}
 
-			t0.Mul(&evalQr[i], &evalR[i])
-			t0.Add(&t0, &t1) // qm.l.r + ql.l + qr.r
+			if evalQr != nil {
+				t1.Mul(&evalQr[i], &evalR[i])
+				t0.Add(&t0, &t1) // qm.l.r + ql.l + qr.r
+			}
 
-			t1.Mul(&evalQo[i], &evalO[i])
-			t0.Add(&t0, &t1)               // ql.l + qr.r + qm.l.r + qo.o
+			if evalQo != nil {
+				t1.Mul(&evalQo[i], &evalO[i])
+				t0.Add(&t0, &t1) // ql.l + qr.r + qm.l.r + qo.o
+			}
 			evalQk[i].Add(&t0, &evalQk[i]) // ql.l + qr.r + qm.l.r + qo.o + k
 		}
 	})
@@ -660,6 +1076,16 @@ func evaluateConstraintsDomainBigBitReversed(pk *ProvingKey, evalL, evalR, evalO
 	return evalQk
 }
 
+// isZeroPolynomial returns true if all the coefficients of p are zero
+func isZeroPolynomial(p []fr.Element) bool {
+	for i := 0; i < len(p); i++ {
+		if !p[i].IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
 // evaluateOrderingDomainBigBitReversed computes the evaluation of Z(uX)g1g2g3-Z(X)f1f2f3 on the odd
 // cosets of the big domain.
 //
@@ -672,11 +1098,14 @@ func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Elemen
 
 	// computes  z_(uX)*(l(X)+s₁(X)*β+γ)*(r(X))+s₂(gⁱ)*β+γ)*(o(X))+s₃(X)*β+γ) - z(X)*(l(X)+X*β+γ)*(r(X)+u*X*β+γ)*(o(X)+u²*X*β+γ)
 	// on the big domain (coset).
-	res := make([]fr.Element, pk.Domain[1].Cardinality)
+	res := getBigDomainBuffer(pk.Domain[1].Cardinality)
 
 	nn := uint64(64 - bits.TrailingZeros64(uint64(nbElmts)))
 
-	// needed to shift evalZ
+	// needed to shift evalZ: μ, the generator of the small domain, is ω^toShift where ω generates
+	// the big domain, so Z(μX) at the i-th point of the coset is Z at the (i+toShift)-th point,
+	// wrapping around the big domain. This holds because the big domain cardinality is a multiple
+	// of the small domain one (see checkDomainRatio).
 	toShift := int(pk.Domain[1].Cardinality / pk.Domain[0].Cardinality)
 
 	var cosetShift, cosetShiftSquare fr.Element
@@ -724,12 +1153,103 @@ func evaluateOrderingDomainBigBitReversed(pk *ProvingKey, z, l, r, o []fr.Elemen
 // Puts the result in res of size n.
 // Warning: result is in bit reversed order, we do a bit reverse operation only once in computeQuotientCanonical
 func evaluateDomainBigBitReversed(poly []fr.Element, domainH *fft.Domain) []fr.Element {
-	res := make([]fr.Element, domainH.Cardinality)
+	res := getBigDomainBuffer(domainH.Cardinality)
 	copy(res, poly)
+	for i := len(poly); i < len(res); i++ {
+		res[i].SetZero()
+	}
 	domainH.FFT(res, fft.DIF, true)
 	return res
 }
 
+// bigDomainBuffers recycles the slices holding evaluations on the big domain across proofs,
+// to reduce the pressure on the garbage collector under sustained proving.
+// It maps a size to a *sync.Pool of *[]fr.Element of that size.
+var bigDomainBuffers sync.Map
+
+// getBigDomainBuffer returns a slice of n elements. If it is recycled, it is not zeroed.
+func getBigDomainBuffer(n uint64) []fr.Element {
+	if p, ok := bigDomainBuffers.Load(n); ok {
+		if b := p.(*sync.Pool).Get(); b != nil {
+			return *(b.(*[]fr.Element))
+		}
+	}
+	return make([]fr.Element, n)
+}
+
+// putBigDomainBuffers makes buffers available to getBigDomainBuffer.
+// The buffers must not be used by the caller afterwards.
+func putBigDomainBuffers(buffers ...[]fr.Element) {
+	for i := 0; i < len(buffers); i++ {
+		b := buffers[i]
+		p, _ := bigDomainBuffers.LoadOrStore(uint64(len(b)), new(sync.Pool))
+		p.(*sync.Pool).Put(&b)
+	}
+}
+
+// bigDomainBytes returns the total size of the distinct buffers
+func bigDomainBytes(buffers ...[]fr.Element) uint64 {
+	var res uint64
+	for i := 0; i < len(buffers); i++ {
+		if len(buffers[i]) == 0 {
+			continue
+		}
+		shared := false
+		for j := 0; j < i && !shared; j++ {
+			shared = len(buffers[j]) != 0 && &buffers[j][0] == &buffers[i][0]
+		}
+		if !shared {
+			res += uint64(len(buffers[i])) * fr.Bytes
+		}
+	}
+	return res
+}
+
+// checkDomainRatio ensures the small domain is a subgroup of the big domain, that is the
+// cardinality of the big domain is a multiple of the small domain one
+func checkDomainRatio(pk *ProvingKey) error {
+	if pk.Domain[0].Cardinality == 0 || pk.Domain[1].Cardinality%pk.Domain[0].Cardinality != 0 {
+		return errInvalidDomainRatio
+	}
+	return nil
+}
+
+// checkDomainCardinality returns ErrDomainTooLarge if a domain of size cardinality can't be
+// indexed by ints bounded by maxInt.
+func checkDomainCardinality(cardinality uint64, maxInt int64) error {
+	if cardinality > uint64(maxInt) {
+		return fmt.Errorf("%w: cardinality %d exceeds %d", ErrDomainTooLarge, cardinality, maxInt)
+	}
+	return nil
+}
+
+// checkBigDomainEvaluations ensures the evaluations on the big domain cached in pk have the
+// size of the big domain, for instance after the domain of a deserialized key was modified
+func checkBigDomainEvaluations(pk *ProvingKey) error {
+	n := int(pk.Domain[1].Cardinality)
+	if len(pk.EvaluationPermutationBigDomainBitReversed) != 3*n {
+		return fmt.Errorf("%w: permutation has %d evaluations, expected %d", errInvalidBigDomainEvaluations, len(pk.EvaluationPermutationBigDomainBitReversed), 3*n)
+	}
+	if len(pk.EvaluationSelectorsDomainBigBitReversed) != 4*n {
+		return fmt.Errorf("%w: selectors have %d evaluations, expected %d", errInvalidBigDomainEvaluations, len(pk.EvaluationSelectorsDomainBigBitReversed), 4*n)
+	}
+	if len(pk.EvaluationQkIncompleteDomainBigBitReversed) != n {
+		return fmt.Errorf("%w: qk has %d evaluations, expected %d", errInvalidBigDomainEvaluations, len(pk.EvaluationQkIncompleteDomainBigBitReversed), n)
+	}
+	return nil
+}
+
+// checkCosetTables ensures the coset tables used by domain.FFT and domain.FFTInverse
+// on a coset are populated
+func checkCosetTables(domain *fft.Domain) error {
+	n := int(domain.Cardinality)
+	if len(domain.CosetTable) != n || len(domain.CosetTableReversed) != n ||
+		len(domain.CosetTableInv) != n || len(domain.CosetTableInvReversed) != n {
+		return errMissingCosetTable
+	}
+	return nil
+}
+
 // evaluateXnMinusOneDomainBigCoset evalutes Xᵐ-1 on DomainBig coset
 func evaluateXnMinusOneDomainBigCoset(domainBig, domainSmall *fft.Domain) []fr.Element {
 
@@ -756,47 +1276,156 @@ func evaluateXnMinusOneDomainBigCoset(domainBig, domainSmall *fft.Domain) []fr.E
 	return res
 }
 
-// computeQuotientCanonical computes h in canonical form, split as h1+X^mh2+X²mh3 such that
+// evaluateLOneDomainBigBitReversed computes the evaluation of L₁, the first Lagrange polynomial
+// on the small domain, on the big domain (coset), in bit reversed order.
+func evaluateLOneDomainBigBitReversed(pk *ProvingKey) []fr.Element {
+
+	// computes L₁ (canonical form)
+	lOne := getBigDomainBuffer(pk.Domain[1].Cardinality)
+	for i := 0; i < int(pk.Domain[0].Cardinality); i++ {
+		lOne[i].Set(&pk.Domain[0].CardinalityInv)
+	}
+	for i := int(pk.Domain[0].Cardinality); i < len(lOne); i++ {
+		lOne[i].SetZero()
+	}
+	pk.Domain[1].FFT(lOne, fft.DIF, true)
+
+	return lOne
+}
+
+// evaluateQkCompleteDomainBigBitReversed computes the evaluation of qk, completed with the
+// public inputs, on the big domain (coset), in bit reversed order.
 //
-// ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X) + α.(z(μX)*g₁(X)*g₂(X)*g₃(X)-z(X)*f₁(X)*f₂(X)*f₃(X)) + α²*L₁(X)*(Z(X)-1)= h(X)Z(X)
+// The completed qk is qkIncomplete + ∑ᵢ publicInputs[i]*Lᵢ₊₁, where the evaluation of
+// qkIncomplete is precomputed in pk. Since Lᵢ₊₁(X) = L₁(ω⁻ⁱX), with ω the generator of
+// the small domain, and ω is the ratio-th power of the generator of the big domain, the
+// evaluation of Lᵢ₊₁ on the big domain is the one of L₁ shifted by i*ratio points. With few
+// public inputs this avoids an inverse FFT on the small domain and an FFT on the big domain.
 //
-// constraintInd, constraintOrdering are evaluated on the big domain (coset).
-func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReversed, evaluationConstraintOrderingBitReversed, evaluationBlindedZDomainBigBitReversed []fr.Element, alpha fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+// * evaluationLOneDomainBigBitReversed evaluation of L₁ on the big domain (coset), in bit reversed order
+func evaluateQkCompleteDomainBigBitReversed(pk *ProvingKey, evaluationLOneDomainBigBitReversed, publicInputs []fr.Element) []fr.Element {
+
+	n := pk.Domain[1].Cardinality
+	res := getBigDomainBuffer(n)
+	copy(res, pk.EvaluationQkIncompleteDomainBigBitReversed)
+
+	// the shifted sums cost n*len(publicInputs) multiplications, the FFTs about (n/2)*log(n)
+	if 2*len(publicInputs) > bits.TrailingZeros64(n) {
+		delta := make([]fr.Element, pk.Domain[0].Cardinality)
+		copyBitReversed(delta, publicInputs)
+		pk.Domain[0].FFTInverse(delta, fft.DIT)
+		evaluationDelta := evaluateDomainBigBitReversed(delta, &pk.Domain[1])
+		utils.Parallelize(len(res), func(start, end int) {
+			for i := start; i < end; i++ {
+				res[i].Add(&res[i], &evaluationDelta[i])
+			}
+		})
+		putBigDomainBuffers(evaluationDelta)
+		return res
+	}
+
+	applyPublicInputDelta(res, evaluationLOneDomainBigBitReversed, publicInputs, n/pk.Domain[0].Cardinality)
 
-	h := make([]fr.Element, pk.Domain[1].Cardinality)
+	return res
+}
+
+// applyPublicInputDelta adds ∑ᵢ publicInputs[i]*Lᵢ₊₁ to qk, in place, where qk is an evaluation
+// on the big domain (coset) in bit reversed order, typically a copy of the incomplete qk cached
+// by Setup in pk.EvaluationQkIncompleteDomainBigBitReversed. Each point gets len(publicInputs)
+// updates, read from the evaluation of L₁ shifted by multiples of ratio, the cardinality of the
+// big domain over the cardinality of the small one.
+func applyPublicInputDelta(qk, evaluationLOneDomainBigBitReversed, publicInputs []fr.Element, ratio uint64) {
+	n := uint64(len(qk))
+	nn := uint64(64 - bits.TrailingZeros64(n))
+
+	utils.Parallelize(len(qk), func(start, end int) {
+		var t fr.Element
+		for i := uint64(start); i < uint64(end); i++ {
+
+			// qk[i] is the evaluation at the k-th point of the coset
+			k := bits.Reverse64(i) >> nn
+
+			for j := 0; j < len(publicInputs); j++ {
+				// Lⱼ₊₁ evaluated at the k-th point is L₁ evaluated at the (k-j*ratio)-th point
+				_k := bits.Reverse64((k-uint64(j)*ratio)&(n-1)) >> nn
+				t.Mul(&publicInputs[j], &evaluationLOneDomainBigBitReversed[_k])
+				qk[i].Add(&qk[i], &t)
+			}
+		}
+	})
+}
+
+// evaluateStartsAtOneDomainBigBitReversed computes the evaluation of L₁(X)*(Z(X)-1) on the
+// big domain (coset), where L₁ is the first Lagrange polynomial on the small domain.
+//
+// * evaluationLOneDomainBigBitReversed evaluation of L₁ on the big domain (coset), in bit reversed order.
+// * evaluationBlindedZDomainBigBitReversed evaluation of the blinded permutation accumulator polynomial
+// on the big domain (coset), in bit reversed order.
+func evaluateStartsAtOneDomainBigBitReversed(evaluationLOneDomainBigBitReversed, evaluationBlindedZDomainBigBitReversed []fr.Element) []fr.Element {
+
+	// L₁(X)*(Z(X)-1) on a coset of the big domain
+	startsAtOne := getBigDomainBuffer(uint64(len(evaluationLOneDomainBigBitReversed)))
+	var one fr.Element
+	one.SetOne()
+	utils.Parallelize(len(startsAtOne), func(start, end int) {
+		var t fr.Element
+		for i := start; i < end; i++ {
+			t.Sub(&evaluationBlindedZDomainBigBitReversed[i], &one)
+			startsAtOne[i].Mul(&evaluationLOneDomainBigBitReversed[i], &t)
+		}
+	})
+
+	return startsAtOne
+}
+
+// computeQuotientCanonical computes h in canonical form, such that
+//
+// identities[0](X) + α*identities[1](X) + α²*identities[2](X) + ... = h(X)Z(X)
+//
+// The standard identities are, in this order:
+//
+// ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X)
+// z(μX)*g₁(X)*g₂(X)*g₃(X)-z(X)*f₁(X)*f₂(X)*f₃(X)
+// L₁(X)*(Z(X)-1)
+//
+// identities are evaluated on the big domain (coset), in bit reversed order.
+//
+// It returns errDegenerateCoset if Xᵐ-1 vanishes on the coset of the big domain, which
+// happens when the coset shift of pk.Domain[1] was set without SetBigDomainCosetShift.
+func computeQuotientCanonical(pk *ProvingKey, identities [][]fr.Element, alpha fr.Element) ([]fr.Element, error) {
 
 	// evaluate Z = Xᵐ-1 on a coset of the big domain
 	evaluationXnMinusOneInverse := evaluateXnMinusOneDomainBigCoset(&pk.Domain[1], &pk.Domain[0])
-	evaluationXnMinusOneInverse = fr.BatchInvert(evaluationXnMinusOneInverse)
 
-	// computes L₁ (canonical form)
-	startsAtOne := make([]fr.Element, pk.Domain[1].Cardinality)
-	for i := 0; i < int(pk.Domain[0].Cardinality); i++ {
-		startsAtOne[i].Set(&pk.Domain[0].CardinalityInv)
+	// fr.BatchInvert doesn't fail on zero entries, it would silently produce a wrong h
+	for i := range evaluationXnMinusOneInverse {
+		if evaluationXnMinusOneInverse[i].IsZero() {
+			return nil, fmt.Errorf("%w: at coset point %d", errDegenerateCoset, i)
+		}
 	}
-	pk.Domain[1].FFT(startsAtOne, fft.DIF, true)
+	evaluationXnMinusOneInverse = fr.BatchInvert(evaluationXnMinusOneInverse)
 
-	// ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X) + α.(z(μX)*g₁(X)*g₂(X)*g₃(X)-z(X)*f₁(X)*f₂(X)*f₃(X)) + α**2*L₁(X)(Z(X)-1)
-	// on a coset of the big domain
-	nn := uint64(64 - bits.TrailingZeros64(pk.Domain[1].Cardinality))
+	h := getBigDomainBuffer(pk.Domain[1].Cardinality)
 
-	var one fr.Element
-	one.SetOne()
+	// ∑ᵢ αⁱ*identities[i](X) on a coset of the big domain
+	nn := uint64(64 - bits.TrailingZeros64(pk.Domain[1].Cardinality))
 
 	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
 
+	last := len(identities) - 1
+
 	utils.Parallelize(int(pk.Domain[1].Cardinality), func(start, end int) {
-		var t fr.Element
 		for i := uint64(start); i < uint64(end); i++ {
 
 			_i := bits.Reverse64(i) >> nn
 
-			t.Sub(&evaluationBlindedZDomainBigBitReversed[_i], &one) // evaluates L₁(X)*(Z(X)-1) on a coset of the big domain
-			h[_i].Mul(&startsAtOne[_i], &alpha).Mul(&h[_i], &t).
-				Add(&h[_i], &evaluationConstraintOrderingBitReversed[_i]).
-				Mul(&h[_i], &alpha).
-				Add(&h[_i], &evaluationConstraintsIndBitReversed[_i]).
-				Mul(&h[_i], &evaluationXnMinusOneInverse[i%ratio])
+			// Horner scheme in α
+			h[_i].Set(&identities[last][_i])
+			for j := last - 1; j >= 0; j-- {
+				h[_i].Mul(&h[_i], &alpha).
+					Add(&h[_i], &identities[j][_i])
+			}
+			h[_i].Mul(&h[_i], &evaluationXnMinusOneInverse[i%ratio])
 		}
 	})
 
@@ -804,13 +1433,55 @@ func computeQuotientCanonical(pk *ProvingKey, evaluationConstraintsIndBitReverse
 	// using fft.DIT put h revert bit reverse
 	pk.Domain[1].FFTInverse(h, fft.DIT, true)
 
-	// degree of hi is n+2 because of the blinding
-	h1 := h[:pk.Domain[0].Cardinality+2]
-	h2 := h[pk.Domain[0].Cardinality+2 : 2*(pk.Domain[0].Cardinality+2)]
-	h3 := h[2*(pk.Domain[0].Cardinality+2) : 3*(pk.Domain[0].Cardinality+2)]
+	return h, nil
+
+}
+
+// checkQuotientSplit returns an error if h (canonical form) is not h1 + Xᵐ*h2 + X²ᵐ*h3.
+//
+// This catches wrong split offsets, as well as a quotient whose degree is higher than expected.
+func checkQuotientSplit(h, h1, h2, h3 []fr.Element, m uint64) error {
+	recombined := make([]fr.Element, len(h))
+	for k, hk := range [][]fr.Element{h1, h2, h3} {
+		offset := uint64(k) * m
+		if offset+uint64(len(hk)) > uint64(len(recombined)) {
+			return fmt.Errorf("%w: h%d doesn't fit at offset %d", errQuotientSplit, k+1, offset)
+		}
+		for i := 0; i < len(hk); i++ {
+			recombined[offset+uint64(i)].Add(&recombined[offset+uint64(i)], &hk[i])
+		}
+	}
+	for i := 0; i < len(h); i++ {
+		if !recombined[i].Equal(&h[i]) {
+			return fmt.Errorf("%w: coefficient %d differs", errQuotientSplit, i)
+		}
+	}
+	return nil
+}
+
+// quotientSplitSize returns the number of coefficients m of h1, h2, h3 in h = h1 + Xᵐ*h2 + X²ᵐ*h3,
+// where n is the size of the small domain and boLRO, boZ the blinding orders of l, r, o and z.
+//
+// The ordering constraint z(X)*f₁(X)*f₂(X)*f₃(X) is of degree (n+boZ) + 3*(n+boLRO), so once
+// divided by Xⁿ-1, h is of degree 3n+boZ+3*boLRO and has 3n+boZ+3*boLRO+1 coefficients.
+// With the default blinding orders (1 and 2) this gives m = n+2.
+func quotientSplitSize(n, boLRO, boZ uint64) uint64 {
+	return n + (boZ+3*boLRO+1+2)/3
+}
+
+// splitQuotient splits h (canonical form) in h1, h2, h3 of quotientSplitSize(n, boLRO, boZ)
+// coefficients each. It returns an error if h doesn't have enough coefficients.
+func splitQuotient(h []fr.Element, n, boLRO, boZ uint64) ([]fr.Element, []fr.Element, []fr.Element, error) {
+	m := quotientSplitSize(n, boLRO, boZ)
+	if uint64(len(h)) < 3*m {
+		return nil, nil, nil, fmt.Errorf("%w: %d coefficients, need %d", errQuotientTooLarge, len(h), 3*m)
+	}
 
-	return h1, h2, h3
+	h1 := h[:m]
+	h2 := h[m : 2*m]
+	h3 := h[2*m : 3*m]
 
+	return h1, h2, h3, nil
 }
 
 // computeLinearizedPolynomial computes the linearized polynomial in canonical basis.
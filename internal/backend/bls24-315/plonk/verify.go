@@ -17,97 +17,233 @@
 package plonk
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io"
 	"math/big"
+	"runtime"
 	"time"
 
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr"
 
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/kzg"
 
+	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fft"
+
 	curve "github.com/consensys/gnark-crypto/ecc/bls24-315"
 
 	bls24_315witness "github.com/consensys/gnark/internal/backend/bls24-315/witness"
 
+	"github.com/consensys/gnark/internal/backend/bls24-315/cs"
+
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark/debug"
+	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 )
 
 var (
 	errWrongClaimedQuotient = errors.New("claimed quotient is not as expected")
+	errWrongNbClaimedValues = errors.New("batched opening proof doesn't have the expected number of claimed values")
+	errInvalidPoint         = errors.New("point is not in the correct subgroup")
+	errInvalidWitnessSize   = errors.New("invalid witness size")
+	errAuditDisabled        = errors.New("AuditProof is only available in debug builds")
+	errAuditPermutation     = errors.New("opening of the permutation doesn't match the proving key")
+	errAuditUndetermined    = errors.New("z(ζ) can't be recovered from the linearized polynomial")
+	errTrailingBytes        = errors.New("trailing bytes after the encoding")
+
+	// ErrPublicWitnessLength is returned by Verify when the public witness doesn't have exactly
+	// vk.NbPublicVariables elements.
+	ErrPublicWitnessLength = errors.New("public witness length doesn't match the verifying key")
 )
 
-func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witness) error {
-	log := logger.Logger().With().Str("curve", "bls24_315").Str("backend", "plonk").Logger()
-	start := time.Now()
-
-	// pick a hash function to derive the challenge (the same as in the prover)
-	hFunc := sha256.New()
+// ExtractPublicWitness returns the public part of fullWitness, as expected by Verify.
+//
+// fullWitness must be ordered as [ public | secret ] and have exactly the number of public and
+// secret variables of spr. The result shares its memory with fullWitness.
+func ExtractPublicWitness(fullWitness bls24_315witness.Witness, spr *cs.SparseR1CS) (bls24_315witness.Witness, error) {
+	if expected := spr.NbPublicVariables + spr.NbSecretVariables; len(fullWitness) != expected {
+		return nil, fmt.Errorf("%w: got %d, expected %d = %d (public) + %d (secret)",
+			errInvalidWitnessSize, len(fullWitness), expected, spr.NbPublicVariables, spr.NbSecretVariables)
+	}
+	return fullWitness[:spr.NbPublicVariables:spr.NbPublicVariables], nil
+}
 
-	// transcript to derive the challenge
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
+func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witness) error {
+	return verify(proof, vk, publicWitness, newVerifierConstants(vk))
+}
 
-	// The first challenge is derived using the public data: the commitments to the permutation,
-	// the coefficients of the circuit, and the public inputs.
-	// derive gamma from the Comm(blinded cl), Comm(blinded cr), Comm(blinded co)
-	if err := bindPublicData(&fs, "gamma", *vk, publicWitness); err != nil {
-		return err
+// VerifyBytes decodes a proof, a verifying key, the kzg SRS and a public witness from their
+// binary encodings (see Proof.WriteTo, VerifyingKey.WriteTo, kzg.SRS.WriteTo and
+// witness.Witness.WriteTo), and verifies the proof. This is the minimal surface for callers
+// which can only exchange bytes, such as foreign function interfaces.
+//
+// The SRS is not part of the encoding of the verifying key, it must be provided as well. Each
+// encoding must be consumed entirely, the error names the one which doesn't decode.
+func VerifyBytes(proofBytes, vkBytes, srsBytes, publicWitnessBytes []byte) error {
+	var proof Proof
+	if err := decodeExactly(&proof, proofBytes); err != nil {
+		return fmt.Errorf("invalid proof: %w", err)
 	}
-	bgamma, err := fs.ComputeChallenge("gamma")
-	if err != nil {
-		return err
+	var vk VerifyingKey
+	if err := decodeExactly(&vk, vkBytes); err != nil {
+		return fmt.Errorf("invalid verifying key: %w", err)
 	}
-	var gamma fr.Element
-	gamma.SetBytes(bgamma)
+	var srs kzg.SRS
+	if err := decodeExactly(&srs, srsBytes); err != nil {
+		return fmt.Errorf("invalid kzg srs: %w", err)
+	}
+	if err := vk.InitKZG(&srs); err != nil {
+		return fmt.Errorf("invalid kzg srs: %w", err)
+	}
+	var publicWitness bls24_315witness.Witness
+	if err := decodeExactly(&publicWitness, publicWitnessBytes); err != nil {
+		return fmt.Errorf("invalid public witness: %w", err)
+	}
+	return Verify(&proof, &vk, publicWitness)
+}
 
-	// derive beta from Comm(l), Comm(r), Comm(o)
-	beta, err := deriveRandomness(&fs, "beta")
+// decodeExactly decodes v from b, and returns an error if b is not consumed entirely
+func decodeExactly(v io.ReaderFrom, b []byte) error {
+	n, err := v.ReadFrom(bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
+	if n != int64(len(b)) {
+		return fmt.Errorf("%w: %d bytes", errTrailingBytes, int64(len(b))-n)
+	}
+	return nil
+}
 
-	// derive alpha from Comm(l), Comm(r), Comm(o), Com(Z)
-	alpha, err := deriveRandomness(&fs, "alpha", &proof.Z)
-	if err != nil {
+// VerifyBatch verifies proofs[i] against publicWitnesses[i] for each i, in parallel, and returns
+// the error of each verification (nil if the proof is valid).
+//
+// Each proof is verified independently, exactly as with Verify: there is no soundness caveat,
+// the values which only depend on vk are computed once for the batch.
+func VerifyBatch(proofs []*Proof, vk *VerifyingKey, publicWitnesses []bls24_315witness.Witness) []error {
+	errs := make([]error, len(proofs))
+	if len(publicWitnesses) != len(proofs) {
+		err := fmt.Errorf("%w: got %d public witnesses for %d proofs", errInvalidWitnessSize, len(publicWitnesses), len(proofs))
+		for i := 0; i < len(errs); i++ {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	c := newVerifierConstants(vk)
+	utils.Parallelize(len(proofs), func(start, end int) {
+		for i := start; i < end; i++ {
+			errs[i] = verify(proofs[i], vk, publicWitnesses[i], c)
+		}
+	}, runtime.NumCPU())
+
+	return errs
+}
+
+// verifierConstants holds the values used by the verifier which only depend on the verifying key
+type verifierConstants struct {
+	size        big.Int    // size of the small domain
+	mPlusTwo    big.Int    // size of the chunks of the quotient, see quotientSplitSize
+	cosetSquare fr.Element // square of vk.CosetShift
+	domain      fft.Domain // small domain, without the precomputed tables, see evalPublicInput
+}
+
+func newVerifierConstants(vk *VerifyingKey) *verifierConstants {
+	var c verifierConstants
+	c.size.SetUint64(vk.Size)
+	c.mPlusTwo.SetUint64(quotientSplitSize(vk.Size, blindingOrder(nbOpeningsLRO), blindingOrder(nbOpeningsZ)))
+	c.cosetSquare.Square(&vk.CosetShift)
+	c.domain.Cardinality = vk.Size
+	c.domain.CardinalityInv.Set(&vk.SizeInv)
+	c.domain.Generator.Set(&vk.Generator)
+	return &c
+}
+
+// evalPublicInput returns PI(ζ), where PI is the polynomial interpolating the public witness on
+// the first len(publicWitness) points of domain, padded with zeroes.
+//
+// It uses the barycentric formula PI(ζ) = (ζⁿ-1)/n * ∑_{i} wᵢ*ωⁱ/(ζ-ωⁱ), with a single batch
+// inversion, in O(len(publicWitness)). Only domain.Cardinality, domain.CardinalityInv and
+// domain.Generator are used.
+func evalPublicInput(publicWitness []fr.Element, domain *fft.Domain, zeta fr.Element) fr.Element {
+	var res fr.Element
+	if len(publicWitness) == 0 {
+		return res
+	}
+
+	// ωⁱ and ζ-ωⁱ
+	roots := make([]fr.Element, len(publicWitness))
+	den := make([]fr.Element, len(publicWitness))
+	roots[0].SetOne()
+	for i := 0; i < len(roots); i++ {
+		if i > 0 {
+			roots[i].Mul(&roots[i-1], &domain.Generator)
+		}
+		den[i].Sub(&zeta, &roots[i])
+		if den[i].IsZero() {
+			// ζ = ωⁱ, Lⱼ(ζ) = δᵢⱼ
+			return publicWitness[i]
+		}
+	}
+	den = fr.BatchInvert(den)
+
+	var tmp fr.Element
+	for i := 0; i < len(publicWitness); i++ {
+		tmp.Mul(&roots[i], &den[i]).Mul(&tmp, &publicWitness[i])
+		res.Add(&res, &tmp)
+	}
+
+	// (ζⁿ-1)/n
+	var zn big.Int
+	zn.SetUint64(domain.Cardinality)
+	tmp.Exp(zeta, &zn)
+	one := fr.One()
+	tmp.Sub(&tmp, &one).Mul(&tmp, &domain.CardinalityInv)
+
+	return *res.Mul(&res, &tmp)
+}
+
+// verify is Verify, with the values which only depend on vk precomputed in c
+func verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witness, c *verifierConstants) error {
+	log := logger.Logger().With().Str("curve", "bls24_315").Str("backend", "plonk").Logger()
+	start := time.Now()
+
+	if uint64(len(publicWitness)) != vk.NbPublicVariables {
+		return fmt.Errorf("%w: got %d, expected %d", ErrPublicWitnessLength, len(publicWitness), vk.NbPublicVariables)
+	}
+
+	// reject malformed proofs before doing any expensive computation
+	if err := proof.quickCheck(); err != nil {
 		return err
 	}
 
-	// derive zeta, the point of evaluation
-	zeta, err := deriveRandomness(&fs, "zeta", &proof.H[0], &proof.H[1], &proof.H[2])
+	// pick a hash function to batch the openings (the same as in the prover)
+	hFunc := sha256.New()
+
+	// challenges, derived with Fiat-Shamir (the same as in the prover)
+	gamma, beta, alpha, zeta, err := deriveChallenges(proof, vk, publicWitness)
 	if err != nil {
 		return err
 	}
 
 	// evaluation of Z=Xⁿ⁻¹ at ζ
 	var zetaPowerM, zzeta fr.Element
-	var bExpo big.Int
 	one := fr.One()
-	bExpo.SetUint64(vk.Size)
-	zetaPowerM.Exp(zeta, &bExpo)
+	zetaPowerM.Exp(zeta, &c.size)
 	zzeta.Sub(&zetaPowerM, &one)
 
-	// ccompute PI = ∑_{i<n} Lᵢ*wᵢ
-	// TODO use batch inversion
-	var pi, den, lagrangeOne, xiLi fr.Element
-	lagrange := zzeta // ζⁿ⁻¹
-	acc := fr.One()
-	den.Sub(&zeta, &acc)
-	lagrange.Div(&lagrange, &den).Mul(&lagrange, &vk.SizeInv) // (1/n)*(ζⁿ⁻¹)/(ζ-1)
-	lagrangeOne.Set(&lagrange)                                // save it for later
-	for i := 0; i < len(publicWitness); i++ {
-
-		xiLi.Mul(&lagrange, &publicWitness[i])
-		pi.Add(&pi, &xiLi)
+	// compute PI = ∑_{i<n} Lᵢ*wᵢ
+	pi := evalPublicInput(publicWitness, &c.domain, zeta)
 
-		// use Lᵢ₊₁ = w*L_i*(X-zⁱ)/(X-zⁱ⁺¹)
-		lagrange.Mul(&lagrange, &vk.Generator).
-			Mul(&lagrange, &den)
-		acc.Mul(&acc, &vk.Generator)
-		den.Sub(&zeta, &acc)
-		lagrange.Div(&lagrange, &den)
-	}
+	// L₁(ζ) = (1/n)*(ζⁿ⁻¹)/(ζ-1), ζ ≠ 1 (see rederiveZeta)
+	var lagrangeOne fr.Element
+	lagrangeOne.Sub(&zeta, &one).
+		Inverse(&lagrangeOne).
+		Mul(&lagrangeOne, &zzeta).
+		Mul(&lagrangeOne, &vk.SizeInv)
 
 	// linearizedpolynomial + pi(ζ) + α*(Z(μζ))*(l(ζ)+β*s1(ζ)+γ)*(r(ζ)+β*s2(ζ)+γ)*(o(ζ)+γ) - α²*L₁(ζ)
 	var _s1, _s2, _o, alphaSquareLagrange fr.Element
@@ -153,9 +289,8 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witne
 	}
 
 	// compute the folded commitment to H: Comm(h₁) + ζᵐ⁺²*Comm(h₂) + ζ²⁽ᵐ⁺²⁾*Comm(h₃)
-	mPlusTwo := big.NewInt(int64(vk.Size) + 2)
 	var zetaMPlusTwo fr.Element
-	zetaMPlusTwo.Exp(zeta, mPlusTwo)
+	zetaMPlusTwo.Exp(zeta, &c.mPlusTwo)
 	var zetaMPlusTwoBigInt big.Int
 	zetaMPlusTwo.ToBigIntRegular(&zetaMPlusTwoBigInt)
 	foldedH := proof.H[2]
@@ -177,16 +312,15 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witne
 
 	// second part: α*( Z(μζ)(l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ)*β*s₃(X)-Z(X)(l(ζ)+β*id_1(ζ)+γ)*(r(ζ)+β*id_2(ζ)+γ)*(o(ζ)+β*id_3(ζ)+γ) ) )
 
-	var u, v, w, cosetsquare fr.Element
+	var u, v, w fr.Element
 	u.Mul(&zu, &beta)
 	v.Mul(&beta, &s1).Add(&v, &l).Add(&v, &gamma)
 	w.Mul(&beta, &s2).Add(&w, &r).Add(&w, &gamma)
 	_s1.Mul(&u, &v).Mul(&_s1, &w).Mul(&_s1, &alpha) // α*Z(μζ)(l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ)*β
 
-	cosetsquare.Square(&vk.CosetShift)
 	u.Mul(&beta, &zeta).Add(&u, &l).Add(&u, &gamma)                         // (l(ζ)+β*ζ+γ)
 	v.Mul(&beta, &zeta).Mul(&v, &vk.CosetShift).Add(&v, &r).Add(&v, &gamma) // (r(ζ)+β*μ*ζ+γ)
-	w.Mul(&beta, &zeta).Mul(&w, &cosetsquare).Add(&w, &o).Add(&w, &gamma)   // (o(ζ)+β*μ²*ζ+γ)
+	w.Mul(&beta, &zeta).Mul(&w, &c.cosetSquare).Add(&w, &o).Add(&w, &gamma) // (o(ζ)+β*μ²*ζ+γ)
 	_s2.Mul(&u, &v).Mul(&_s2, &w).Neg(&_s2)                                 // -(l(ζ)+β*ζ+γ)*(r(ζ)+β*u*ζ+γ)*(o(ζ)+β*u²*ζ+γ)
 
 	// note since third part =  α²*L₁(ζ)*Z
@@ -246,6 +380,125 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witne
 	return err
 }
 
+// quickCheck cheaply rejects proofs which can't be valid, whatever the verifying key:
+// * the batched opening proof must have a claimed value for each of h, the linearized
+// polynomial, l, r, o, s1, s2 (a proof generated with backend.WithCommitOnly has none)
+// * all the points must be on the curve, in the correct subgroup
+//
+// Points decoded by Proof.ReadFrom or Proof.SetHexMap are already checked, but a proof
+// may be built by other means.
+func (proof *Proof) quickCheck() error {
+	if len(proof.BatchedProof.ClaimedValues) != 7 {
+		return errWrongNbClaimedValues
+	}
+	for name, p := range proof.points() {
+		if !p.IsOnCurve() || !p.IsInSubGroup() {
+			return fmt.Errorf("%w: %s", errInvalidPoint, name)
+		}
+	}
+	return nil
+}
+
+// deriveChallenges returns the challenges γ, β, α, ζ of proof, derived with Fiat-Shamir
+func deriveChallenges(proof *Proof, vk *VerifyingKey, publicWitness []fr.Element) (gamma, beta, alpha, zeta fr.Element, err error) {
+	// transcript to derive the challenges, bound to the public data
+	fs, err := newTranscript(vk, publicWitness, proof.Nonce)
+	if err != nil {
+		return
+	}
+
+	// derive gamma from the public data
+	bgamma, err := fs.ComputeChallenge("gamma")
+	if err != nil {
+		return
+	}
+	gamma.SetBytes(bgamma)
+
+	// derive beta from Comm(l), Comm(r), Comm(o)
+	if beta, err = deriveRandomness(fs, "beta"); err != nil {
+		return
+	}
+
+	// derive alpha from Comm(l), Comm(r), Comm(o), Com(Z)
+	if alpha, err = deriveRandomness(fs, "alpha", &proof.Z); err != nil {
+		return
+	}
+
+	// derive zeta, the point of evaluation
+	if zeta, err = deriveRandomness(fs, "zeta", &proof.H[0], &proof.H[1], &proof.H[2]); err != nil {
+		return
+	}
+	zeta = rederiveZeta(zeta, new(big.Int).SetUint64(vk.Size))
+	return
+}
+
+// rederiveZeta returns zeta if it isn't a root of Xⁿ-1, otherwise the first of sha256(zeta),
+// sha256(sha256(zeta)), ... which isn't. On the small domain (ζ = 1 in particular) ζⁿ-1 and ζ-1
+// vanish, and the verifier could recover neither L₁(ζ) nor h(ζ). This happens with negligible
+// probability, the prover and the verifier re-derive ζ the same way.
+func rederiveZeta(zeta fr.Element, n *big.Int) fr.Element {
+	one := fr.One()
+	var zn fr.Element
+	for {
+		if zn.Exp(zeta, n); !zn.Equal(&one) {
+			return zeta
+		}
+		h := sha256.Sum256(zeta.Marshal())
+		zeta.SetBytes(h[:])
+	}
+}
+
+// ToVerifierInputs returns the inputs of an in-circuit verifier of proof, in the order in which
+// it consumes them:
+//
+//	scalars: [0] β, [1] γ, [2] α, [3] ζ, then the 8 openings of Proof.ToWitness
+//	points:  [0] Comm(l), [1] Comm(r), [2] Comm(o), [3] Comm(z),
+//	         [4] Comm(h1), [5] Comm(h2), [6] Comm(h3),
+//	         [7] the batched opening proof at ζ, [8] the opening proof of z at μζ
+//
+// The challenges are derived from the Fiat-Shamir transcript like Verify does, so they depend on
+// vk and on the public witness.
+func (proof *Proof) ToVerifierInputs(vk *VerifyingKey, publicWitness bls24_315witness.Witness) ([]fr.Element, []curve.G1Affine, error) {
+	if uint64(len(publicWitness)) != vk.NbPublicVariables {
+		return nil, nil, fmt.Errorf("%w: got %d, expected %d", ErrPublicWitnessLength, len(publicWitness), vk.NbPublicVariables)
+	}
+	gamma, beta, alpha, zeta, err := deriveChallenges(proof, vk, publicWitness)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scalars := make([]fr.Element, 0, 4+nbWitnessElements)
+	scalars = append(scalars, beta, gamma, alpha, zeta)
+	scalars = append(scalars, proof.ToWitness()...)
+
+	points := []curve.G1Affine{
+		proof.LRO[0], proof.LRO[1], proof.LRO[2],
+		proof.Z,
+		proof.H[0], proof.H[1], proof.H[2],
+		proof.BatchedProof.H,
+		proof.ZShiftedOpening.H,
+	}
+	return scalars, points, nil
+}
+
+// newTranscript returns the Fiat Shamir transcript shared by the prover and the verifier.
+//
+// It fixes the challenges, in the order they are derived (gamma, beta, alpha, zeta), and binds
+// the public data to gamma: the nonce if any (see backend.WithNonce), the commitments to the
+// permutation, the coefficients of the circuit, and the public inputs.
+func newTranscript(vk *VerifyingKey, publicInputs []fr.Element, nonce []byte) (*fiatshamir.Transcript, error) {
+	fs := fiatshamir.NewTranscript(sha256.New(), "gamma", "beta", "alpha", "zeta")
+	if len(nonce) != 0 {
+		if err := fs.Bind("gamma", nonce); err != nil {
+			return nil, err
+		}
+	}
+	if err := bindPublicData(&fs, "gamma", *vk, publicInputs); err != nil {
+		return nil, err
+	}
+	return &fs, nil
+}
+
 func bindPublicData(fs *fiatshamir.Transcript, challenge string, vk VerifyingKey, publicInputs []fr.Element) error {
 
 	// permutation
@@ -259,6 +512,13 @@ func bindPublicData(fs *fiatshamir.Transcript, challenge string, vk VerifyingKey
 		return err
 	}
 
+	// coset shift, if it's not the default one (see SetupWithCosetShift)
+	if !vk.CosetShift.Equal(&defaultCosetShift) {
+		if err := fs.Bind(challenge, vk.CosetShift.Marshal()); err != nil {
+			return err
+		}
+	}
+
 	// coefficients
 	if err := fs.Bind(challenge, vk.Ql.Marshal()); err != nil {
 		return err
@@ -306,3 +566,148 @@ func deriveRandomness(fs *fiatshamir.Transcript, challenge string, points ...*cu
 	r.SetBytes(b)
 	return r, nil
 }
+
+// ProofAudit holds the identities of the quotient at ζ, recomputed from the proving key and the
+// openings of a proof, see AuditProof.
+type ProofAudit struct {
+	Gamma, Beta, Alpha, Zeta fr.Element // challenges of the proof
+
+	// Z is z(ζ). It isn't opened by the proof, it is recovered from the opening of the
+	// linearized polynomial, in which it appears linearly.
+	Z fr.Element
+
+	Constraints fr.Element // ql(ζ)l(ζ)+qr(ζ)r(ζ)+qm(ζ)l(ζ)r(ζ)+qo(ζ)o(ζ)+qk(ζ), qk completed with the public inputs
+	Ordering    fr.Element // z(μζ)*g₁(ζ)*g₂(ζ)*g₃(ζ)-z(ζ)*f₁(ζ)*f₂(ζ)*f₃(ζ)
+	StartsAtOne fr.Element // L₁(ζ)*(z(ζ)-1)
+
+	// Quotient is h(ζ)*(ζⁿ-1), h(ζ) as claimed by the proof. It is equal to
+	// Constraints + α*Ordering + α²*StartsAtOne when the claimed quotient is correct.
+	Quotient fr.Element
+}
+
+// AuditProof recomputes at ζ, from the proving key and the openings of proof, the identities the
+// prover evaluates on the big domain and divides by Xⁿ-1 to get the quotient h.
+//
+// It is a diagnostic for a suspicious proof: it checks that the openings of s₁ and s₂ are the
+// ones of the proving key, and breaks the check of the claimed quotient down to the identities.
+// When the claimed quotient is wrong, the audit is returned along with the error. The KZG
+// openings are not checked, see Verify.
+//
+// It is only available in debug builds (-tags debug).
+func AuditProof(proof *Proof, pk *ProvingKey, publicWitness bls24_315witness.Witness) (*ProofAudit, error) {
+	if !debug.Debug {
+		return nil, errAuditDisabled
+	}
+	return auditProof(proof, pk, publicWitness)
+}
+
+func auditProof(proof *Proof, pk *ProvingKey, publicWitness bls24_315witness.Witness) (*ProofAudit, error) {
+	vk := pk.Vk
+	if uint64(len(publicWitness)) != vk.NbPublicVariables {
+		return nil, fmt.Errorf("%w: got %d, expected %d", ErrPublicWitnessLength, len(publicWitness), vk.NbPublicVariables)
+	}
+	if err := proof.quickCheck(); err != nil {
+		return nil, err
+	}
+
+	var a ProofAudit
+	var err error
+	if a.Gamma, a.Beta, a.Alpha, a.Zeta, err = deriveChallenges(proof, vk, publicWitness); err != nil {
+		return nil, err
+	}
+	gamma, beta, alpha, zeta := a.Gamma, a.Beta, a.Alpha, a.Zeta
+	c := newVerifierConstants(vk)
+	one := fr.One()
+
+	h := proof.BatchedProof.ClaimedValues[0]
+	linearizedPolynomialZeta := proof.BatchedProof.ClaimedValues[1]
+	l := proof.BatchedProof.ClaimedValues[2]
+	r := proof.BatchedProof.ClaimedValues[3]
+	o := proof.BatchedProof.ClaimedValues[4]
+	s1 := proof.BatchedProof.ClaimedValues[5]
+	s2 := proof.BatchedProof.ClaimedValues[6]
+	zu := proof.ZShiftedOpening.ClaimedValue
+
+	// the openings of s₁ and s₂ must be the ones of the proving key
+	if v := eval(pk.S1Canonical, zeta); !v.Equal(&s1) {
+		return nil, fmt.Errorf("%w: s1", errAuditPermutation)
+	}
+	if v := eval(pk.S2Canonical, zeta); !v.Equal(&s2) {
+		return nil, fmt.Errorf("%w: s2", errAuditPermutation)
+	}
+	s3 := eval(pk.S3Canonical, zeta)
+
+	// ql(ζ)l(ζ)+qr(ζ)r(ζ)+qm(ζ)l(ζ)r(ζ)+qo(ζ)o(ζ)+qk(ζ)
+	ql, qr, qm, qo, qk := eval(pk.Ql, zeta), eval(pk.Qr, zeta), eval(pk.Qm, zeta), eval(pk.Qo, zeta), eval(pk.CQk, zeta)
+	var t fr.Element
+	a.Constraints.Mul(&ql, &l)
+	t.Mul(&qr, &r)
+	a.Constraints.Add(&a.Constraints, &t)
+	t.Mul(&qm, &l).Mul(&t, &r)
+	a.Constraints.Add(&a.Constraints, &t)
+	t.Mul(&qo, &o)
+	a.Constraints.Add(&a.Constraints, &t).Add(&a.Constraints, &qk)
+	pi := evalPublicInput(publicWitness, &c.domain, zeta)
+
+	// g₁(ζ)*g₂(ζ) = (l(ζ)+β*s₁(ζ)+γ)*(r(ζ)+β*s₂(ζ)+γ), g₃(ζ) = o(ζ)+β*s₃(ζ)+γ
+	var g, g3 fr.Element
+	g.Mul(&beta, &s1).Add(&g, &l).Add(&g, &gamma)
+	t.Mul(&beta, &s2).Add(&t, &r).Add(&t, &gamma)
+	g.Mul(&g, &t)
+	g3.Mul(&beta, &s3).Add(&g3, &o).Add(&g3, &gamma)
+
+	// f₁(ζ)*f₂(ζ)*f₃(ζ) = (l(ζ)+β*ζ+γ)*(r(ζ)+β*μ*ζ+γ)*(o(ζ)+β*μ²*ζ+γ)
+	var f fr.Element
+	f.Mul(&beta, &zeta).Add(&f, &l).Add(&f, &gamma)
+	t.Mul(&beta, &zeta).Mul(&t, &vk.CosetShift).Add(&t, &r).Add(&t, &gamma)
+	f.Mul(&f, &t)
+	t.Mul(&beta, &zeta).Mul(&t, &c.cosetSquare).Add(&t, &o).Add(&t, &gamma)
+	f.Mul(&f, &t)
+
+	// ζⁿ-1 and L₁(ζ) = (1/n)*(ζⁿ-1)/(ζ-1)
+	var zn, lagrangeOne fr.Element
+	zn.Exp(zeta, &c.size).Sub(&zn, &one)
+	lagrangeOne.Sub(&zeta, &one).
+		Inverse(&lagrangeOne).
+		Mul(&lagrangeOne, &zn).
+		Mul(&lagrangeOne, &vk.SizeInv)
+
+	// the linearized polynomial at ζ is (see Verify)
+	// 		ql(ζ)l(ζ)+qr(ζ)r(ζ)+qm(ζ)l(ζ)r(ζ)+qo(ζ)o(ζ)+qk(ζ) + α*z(μζ)*g₁(ζ)*g₂(ζ)*β*s₃(ζ) +
+	// 		(α²*L₁(ζ)-α*f₁(ζ)*f₂(ζ)*f₃(ζ))*z(ζ)
+	// where qk isn't completed with the public inputs
+	var num, den fr.Element
+	num.Mul(&g, &zu).Mul(&num, &beta).Mul(&num, &s3).Mul(&num, &alpha).
+		Add(&num, &a.Constraints)
+	num.Sub(&linearizedPolynomialZeta, &num)
+	den.Mul(&f, &alpha)
+	t.Mul(&lagrangeOne, &alpha).Mul(&t, &alpha)
+	den.Sub(&t, &den)
+	if den.IsZero() {
+		return nil, errAuditUndetermined
+	}
+	a.Z.Div(&num, &den)
+	a.Constraints.Add(&a.Constraints, &pi)
+
+	// z(μζ)*g₁(ζ)*g₂(ζ)*g₃(ζ)-z(ζ)*f₁(ζ)*f₂(ζ)*f₃(ζ)
+	a.Ordering.Mul(&g, &g3).Mul(&a.Ordering, &zu)
+	t.Mul(&a.Z, &f)
+	a.Ordering.Sub(&a.Ordering, &t)
+
+	// L₁(ζ)*(z(ζ)-1)
+	a.StartsAtOne.Sub(&a.Z, &one).Mul(&a.StartsAtOne, &lagrangeOne)
+
+	a.Quotient.Mul(&h, &zn)
+
+	// Constraints + α*Ordering + α²*StartsAtOne
+	var expected fr.Element
+	expected.Mul(&a.StartsAtOne, &alpha).
+		Add(&expected, &a.Ordering).
+		Mul(&expected, &alpha).
+		Add(&expected, &a.Constraints)
+	if !expected.Equal(&a.Quotient) {
+		return &a, errWrongClaimedQuotient
+	}
+
+	return &a, nil
+}
@@ -17,13 +17,27 @@
 package plonk
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"math/big"
+	"sync"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	kzgg "github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/logger"
+)
+
+var (
+	errScalarFieldMismatch = errors.New("constraint system was compiled for a curve with a different scalar field")
+	errCircuitMismatch     = errors.New("constraint system doesn't match the one of the proving key setup")
+	errWireOutOfRange      = errors.New("constraint references a wire which is not a variable of the constraint system")
 )
 
 // ProvingKey stores the data needed to generate a proof:
@@ -45,6 +59,18 @@ type ProvingKey struct {
 	// Storing LQk in Lagrange basis saves a fft...
 	CQk, LQk []fr.Element
 
+	// evaluation of the incomplete qk (CQk) on the big domain (coset), in bit reversed order.
+	// The prover only adds the contribution of the public inputs to it.
+	EvaluationQkIncompleteDomainBigBitReversed []fr.Element
+
+	// evaluations of ql, qr, qm, qo on the big domain (coset), in bit reversed order,
+	// concatenated in this order
+	EvaluationSelectorsDomainBigBitReversed []fr.Element
+
+	// ZeroSelectors[i] is true if the i-th of ql, qr, qm, qo is identically zero, the prover
+	// skips its term in the gate constraint. Set along with EvaluationSelectorsDomainBigBitReversed.
+	ZeroSelectors [4]bool
+
 	// Domains used for the FFTs.
 	// Domain[0] = small Domain
 	// Domain[1] = big Domain
@@ -57,6 +83,14 @@ type ProvingKey struct {
 
 	// position -> permuted position (position in [0,3*sizeSystem-1])
 	Permutation []int64
+
+	// CircuitDigest identifies the constraint system of the setup, see circuitDigest.
+	// Prove rejects another constraint system; it is not checked if it's zero.
+	CircuitDigest [32]byte
+
+	// scratchLock guards the evaluations on the big domain when Prove recomputes them, so that
+	// Prove can run concurrently on the same key (see restoreBigDomainEvaluations)
+	scratchLock sync.Mutex
 }
 
 // VerifyingKey stores the data needed to verify a proof:
@@ -87,27 +121,69 @@ type VerifyingKey struct {
 
 // Setup sets proving and verifying keys
 func Setup(spr *cs.SparseR1CS, srs *kzg.SRS) (*ProvingKey, *VerifyingKey, error) {
+	return setup(spr, srs, nil, 0)
+}
+
+// SetupWithCosetShift is Setup, with cosetShift in place of the multiplicative generator of fr
+// as the representative of the cosets of the permutation (vk.CosetShift) and of the coset of the
+// big domain on which the quotient is computed.
+//
+// Circuits set up with distinct coset shifts have distinct cosets, which prevents the proofs of
+// circuits proven in the same aggregation from being confused. A coset shift other than the
+// default one is bound to the Fiat-Shamir transcript. cosetShift must be such that Xⁿ-1 doesn't
+// vanish on the big domain coset, where n is the size of the small domain.
+func SetupWithCosetShift(spr *cs.SparseR1CS, srs *kzg.SRS, cosetShift fr.Element) (*ProvingKey, *VerifyingKey, error) {
+	return setup(spr, srs, &cosetShift, 0)
+}
+
+// setup is Setup, with the default coset shift if cosetShift is nil. The small domain holds at
+// least minSize rows, the tests of GrowDomain compare its keys with the ones of setup.
+func setup(spr *cs.SparseR1CS, srs *kzg.SRS, cosetShift *fr.Element, minSize uint64) (*ProvingKey, *VerifyingKey, error) {
 	var pk ProvingKey
 	var vk VerifyingKey
 
+	if err := checkScalarField(spr); err != nil {
+		return nil, nil, err
+	}
+	if err := checkWireIDs(spr); err != nil {
+		return nil, nil, err
+	}
+
 	// The verifying key shares data with the proving key
 	pk.Vk = &vk
+	pk.CircuitDigest = circuitDigest(spr)
 
 	nbConstraints := len(spr.Constraints)
 
 	// fft domains
 	sizeSystem := uint64(nbConstraints + spr.NbPublicVariables) // spr.NbPublicVariables is for the placeholder constraints
-	pk.Domain[0] = *fft.NewDomain(sizeSystem)
-	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
+	if sizeSystem < minSize {
+		sizeSystem = minSize
+	}
+	pk.Domain[0] = *getDomain(sizeSystem)
+
+	// the prover work is proportional to the size of the domain, not to the number of constraints
+	if isDomainUnderused(sizeSystem, pk.Domain[0].Cardinality) {
+		log := logger.Logger().With().Str("curve", spr.CurveID().String()).Str("backend", "plonk").Logger()
+		log.Warn().Uint64("nbConstraints", sizeSystem).Uint64("domainSize", pk.Domain[0].Cardinality).
+			Msg("circuit fills less than 60% of the fft domain, the prover will mostly work on padding: consider batching more constraints")
+	}
 
 	// h, the quotient polynomial is of degree 3(n+1)+2, so it's in a 3(n+2) dim vector space,
 	// the domain is the next power of 2 superior to 3(n+2). 4*domainNum is enough in all cases
 	// except when n<6.
 	if sizeSystem < 6 {
-		pk.Domain[1] = *fft.NewDomain(8 * sizeSystem)
+		pk.Domain[1] = *getDomain(8 * sizeSystem)
 	} else {
-		pk.Domain[1] = *fft.NewDomain(4 * sizeSystem)
+		pk.Domain[1] = *getDomain(4 * sizeSystem)
 	}
+	if cosetShift != nil {
+		if err := pk.SetBigDomainCosetShift(*cosetShift); err != nil {
+			return nil, nil, err
+		}
+		setDomainCosetShift(&pk.Domain[0], *cosetShift)
+	}
+	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
 
 	vk.Size = pk.Domain[0].Cardinality
 	vk.SizeInv.SetUint64(vk.Size).Inverse(&vk.SizeInv)
@@ -157,6 +233,10 @@ func Setup(spr *cs.SparseR1CS, srs *kzg.SRS) (*ProvingKey, *VerifyingKey, error)
 	fft.BitReverse(pk.Qo)
 	fft.BitReverse(pk.CQk)
 
+	// evaluation of the selectors on the big domain, and of qk without the public inputs
+	computeSelectorsBigDomain(&pk)
+	computeQkIncompleteBigDomain(&pk)
+
 	// build permutation. Note: at this stage, the permutation takes in account the placeholders
 	buildPermutation(spr, &pk)
 
@@ -164,34 +244,44 @@ func Setup(spr *cs.SparseR1CS, srs *kzg.SRS) (*ProvingKey, *VerifyingKey, error)
 	ccomputePermutationPolynomials(&pk)
 
 	// Commit to the polynomials to set up the verifying key
+	if err := commitSetupPolynomials(&pk); err != nil {
+		return nil, nil, err
+	}
+
+	return &pk, &vk, nil
+
+}
+
+// commitSetupPolynomials sets the commitments of pk.Vk to the selectors and to the permutation
+// polynomials of pk
+func commitSetupPolynomials(pk *ProvingKey) error {
+	vk := pk.Vk
 	var err error
 	if vk.Ql, err = kzg.Commit(pk.Ql, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.Qr, err = kzg.Commit(pk.Qr, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.Qm, err = kzg.Commit(pk.Qm, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.Qo, err = kzg.Commit(pk.Qo, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.Qk, err = kzg.Commit(pk.CQk, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.S[0], err = kzg.Commit(pk.S1Canonical, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.S[1], err = kzg.Commit(pk.S2Canonical, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
 	if vk.S[2], err = kzg.Commit(pk.S3Canonical, vk.KZGSRS); err != nil {
-		return nil, nil, err
+		return err
 	}
-
-	return &pk, &vk, nil
-
+	return nil
 }
 
 // buildPermutation builds the Permutation associated with a circuit.
@@ -217,8 +307,12 @@ func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey) {
 		pk.Permutation[i] = -1
 	}
 
-	// init LRO position -> variable_ID
+	// init LRO position -> variable_ID, the positions which are not associated to a
+	// variable (r, o of the placeholders and the padding) are marked with -1
 	lro := make([]int, 3*sizeSolution) // position -> variable_ID
+	for i := 0; i < len(lro); i++ {
+		lro[i] = -1
+	}
 	for i := 0; i < spr.NbPublicVariables; i++ {
 		lro[i] = i // IDs of LRO associated to placeholders (only L needs to be taken care of)
 	}
@@ -238,6 +332,11 @@ func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey) {
 	}
 
 	for i := 0; i < len(lro); i++ {
+		if lro[i] == -1 {
+			// not associated to a variable, so it's a fixed point
+			pk.Permutation[i] = int64(i)
+			continue
+		}
 		if cycle[lro[i]] != -1 {
 			// if != -1, it means we already encountered this value
 			// so we need to set the corresponding permutation index.
@@ -289,6 +388,40 @@ func ccomputePermutationPolynomials(pk *ProvingKey) {
 	fft.BitReverse(pk.S3Canonical)
 
 	// evaluation of permutation on the big domain
+	computePermutationBigDomain(pk)
+
+}
+
+// computeQkIncompleteBigDomain evaluates the incomplete qk on the big domain (coset) from its
+// canonical form pk.CQk and stores the result, in bit reversed order, in
+// pk.EvaluationQkIncompleteDomainBigBitReversed
+func computeQkIncompleteBigDomain(pk *ProvingKey) {
+	pk.EvaluationQkIncompleteDomainBigBitReversed = make([]fr.Element, pk.Domain[1].Cardinality)
+	copy(pk.EvaluationQkIncompleteDomainBigBitReversed, pk.CQk)
+	pk.Domain[1].FFT(pk.EvaluationQkIncompleteDomainBigBitReversed, fft.DIF, true)
+}
+
+// computeSelectorsBigDomain evaluates ql, qr, qm, qo on the big domain (coset) from their
+// canonical form and stores the result, in bit reversed order, in
+// pk.EvaluationSelectorsDomainBigBitReversed. It sets pk.ZeroSelectors as well.
+func computeSelectorsBigDomain(pk *ProvingKey) {
+	n := pk.Domain[1].Cardinality
+	pk.EvaluationSelectorsDomainBigBitReversed = make([]fr.Element, 4*n)
+	for i, q := range [][]fr.Element{pk.Ql, pk.Qr, pk.Qm, pk.Qo} {
+		// the evaluations of an identically zero selector are zero
+		pk.ZeroSelectors[i] = isZeroPolynomial(q)
+		if pk.ZeroSelectors[i] {
+			continue
+		}
+		copy(pk.EvaluationSelectorsDomainBigBitReversed[uint64(i)*n:], q)
+		pk.Domain[1].FFT(pk.EvaluationSelectorsDomainBigBitReversed[uint64(i)*n:uint64(i+1)*n], fft.DIF, true)
+	}
+}
+
+// computePermutationBigDomain evaluates s1, s2, s3 on the big domain (coset) from their
+// canonical form and stores the result, in bit reversed order, in
+// pk.EvaluationPermutationBigDomainBitReversed
+func computePermutationBigDomain(pk *ProvingKey) {
 	pk.EvaluationPermutationBigDomainBitReversed = make([]fr.Element, 3*pk.Domain[1].Cardinality)
 	copy(pk.EvaluationPermutationBigDomainBitReversed, pk.S1Canonical)
 	copy(pk.EvaluationPermutationBigDomainBitReversed[pk.Domain[1].Cardinality:], pk.S2Canonical)
@@ -299,6 +432,45 @@ func ccomputePermutationPolynomials(pk *ProvingKey) {
 
 }
 
+// domains caches the fft domains created by Setup, so that the setups of circuits of the same
+// size share the twiddle factors and the coset tables.
+// It maps a cardinality to a *domainCacheEntry.
+//
+// The entries are never evicted: every cardinality used by Setup or GrowDomain, including the
+// intermediate ones of successive GrowDomain calls, stays in memory until ClearDomainCache.
+var domains sync.Map
+
+type domainCacheEntry struct {
+	once   sync.Once
+	domain *fft.Domain
+}
+
+// getDomain returns a domain equal to fft.NewDomain(m), created once per cardinality.
+//
+// The tables of the returned domain are shared: they must not be modified in place. The
+// ProvingKey holds a copy of the domain, whose fields can be set (see SetBigDomainCosetShift).
+func getDomain(m uint64) *fft.Domain {
+	cardinality := ecc.NextPowerOfTwo(m)
+	e, _ := domains.LoadOrStore(cardinality, new(domainCacheEntry))
+	entry := e.(*domainCacheEntry)
+	entry.once.Do(func() {
+		entry.domain = fft.NewDomain(cardinality)
+	})
+	return entry.domain
+}
+
+// ClearDomainCache releases the fft domains cached by Setup and GrowDomain. The proving keys
+// which use them keep their own reference, a domain is freed once no key uses it anymore.
+//
+// The following calls to Setup and GrowDomain create new domains, which aren't shared with
+// the keys set up before.
+func ClearDomainCache() {
+	domains.Range(func(cardinality, _ interface{}) bool {
+		domains.Delete(cardinality)
+		return true
+	})
+}
+
 // getIDSmallDomain returns the Lagrange form of ID on the small domain
 func getIDSmallDomain(domain *fft.Domain) []fr.Element {
 
@@ -347,7 +519,303 @@ func (vk *VerifyingKey) NbPublicWitness() int {
 	return int(vk.NbPublicVariables)
 }
 
+// SetBigDomainCosetShift sets the representative of the coset of the big domain (pk.Domain[1])
+// on which the quotient is computed. By default it is the multiplicative generator chosen by
+// fft.NewDomain.
+//
+// The proof doesn't depend on this choice; it allows to align the big domain coset with the
+// one used by another proof. cosetShift must be such that Xⁿ-1 doesn't vanish on the coset,
+// where n is the size of the small domain.
+func (pk *ProvingKey) SetBigDomainCosetShift(cosetShift fr.Element) error {
+	domain := &pk.Domain[1]
+
+	// the coset must be disjoint from the big domain (which contains the small one),
+	// that is cosetShiftᴺ ≠ 1 where N is the size of the big domain
+	var t fr.Element
+	one := fr.One()
+	t.Exp(cosetShift, new(big.Int).SetUint64(domain.Cardinality))
+	if cosetShift.IsZero() || t.Equal(&one) {
+		return errors.New("invalid coset shift: Xⁿ-1 vanishes on the big domain coset")
+	}
+
+	setDomainCosetShift(domain, cosetShift)
+
+	// the evaluations of the permutation, the selectors and qk on the big domain depend on the coset
+	if pk.EvaluationPermutationBigDomainBitReversed != nil {
+		computePermutationBigDomain(pk)
+	}
+	if pk.EvaluationSelectorsDomainBigBitReversed != nil {
+		computeSelectorsBigDomain(pk)
+	}
+	if pk.EvaluationQkIncompleteDomainBigBitReversed != nil {
+		computeQkIncompleteBigDomain(pk)
+	}
+
+	return nil
+}
+
+// GrowDomain lays out the circuit of pk on a small domain of cardinality newSmallCard (rounded
+// up to a power of 2), without running Setup again: the rows added to the domain are padding
+// rows, with zero selectors and out of any copy constraint. The selectors and the permutation
+// polynomials are interpolated again from their values on the former domain, their evaluations
+// on the big domain and the commitments of pk.Vk are recomputed, the fft domains are shared
+// with the other proving keys of the same size. A custom big domain coset shift is kept.
+//
+// pk.Vk is updated in place; it must have been initialized with a large enough SRS (see
+// InitKZG). The padding rows of Setup are zero and out of any copy constraint as well, so the
+// resulting keys are the ones Setup would return on the larger domain.
+//
+// The circuit itself can't change: when constraints are added, the selectors, the permutation
+// and the circuit digest change, so a full Setup is still required.
+func GrowDomain(pk *ProvingKey, newSmallCard uint64) error {
+	n := pk.Domain[0].Cardinality
+	newCard := ecc.NextPowerOfTwo(newSmallCard)
+	if newCard < n {
+		return fmt.Errorf("can't shrink the domain from %d to %d", n, newCard)
+	}
+	if newCard == n {
+		return nil
+	}
+	if pk.Vk.KZGSRS == nil || uint64(len(pk.Vk.KZGSRS.G1)) < newCard {
+		return errors.New("kzg srs is too small")
+	}
+
+	// work on copies, so that pk is left untouched on error. All the fields but the
+	// circuit digest are recomputed.
+	vk := *pk.Vk
+	grown := ProvingKey{Vk: &vk, CircuitDigest: pk.CircuitDigest}
+
+	// fft domains, with the same rule as Setup
+	grown.Domain[0] = *getDomain(newCard)
+	if !vk.CosetShift.Equal(&grown.Domain[0].FrMultiplicativeGen) {
+		setDomainCosetShift(&grown.Domain[0], vk.CosetShift)
+	}
+	if newCard < 6 {
+		grown.Domain[1] = *getDomain(8 * newCard)
+	} else {
+		grown.Domain[1] = *getDomain(4 * newCard)
+	}
+	vk.Size = newCard
+	vk.SizeInv.SetUint64(vk.Size).Inverse(&vk.SizeInv)
+	vk.Generator.Set(&grown.Domain[0].Generator)
+
+	// selectors, in canonical form, except lqk which is in Lagrange form
+	grown.Ql = growPolynomial(pk.Ql, &pk.Domain[0], &grown.Domain[0])
+	grown.Qr = growPolynomial(pk.Qr, &pk.Domain[0], &grown.Domain[0])
+	grown.Qm = growPolynomial(pk.Qm, &pk.Domain[0], &grown.Domain[0])
+	grown.Qo = growPolynomial(pk.Qo, &pk.Domain[0], &grown.Domain[0])
+	grown.CQk = growPolynomial(pk.CQk, &pk.Domain[0], &grown.Domain[0])
+	grown.LQk = make([]fr.Element, newCard)
+	copy(grown.LQk, pk.LQk)
+
+	// permutation: the i-th entry of l (resp. r, o) stays the i-th entry of l (resp. r, o),
+	// the entries of the padding rows are fixed points
+	grown.Permutation = make([]int64, 3*newCard)
+	for i := range grown.Permutation {
+		grown.Permutation[i] = int64(i)
+	}
+	for i, s := range pk.Permutation {
+		grown.Permutation[uint64(i)/n*newCard+uint64(i)%n] = int64(uint64(s)/n*newCard + uint64(s)%n)
+	}
+
+	// evaluations on the big domain, on the coset of pk if it was customized
+	if !pk.Domain[1].FrMultiplicativeGen.Equal(&grown.Domain[1].FrMultiplicativeGen) {
+		if err := grown.SetBigDomainCosetShift(pk.Domain[1].FrMultiplicativeGen); err != nil {
+			return err
+		}
+	}
+	ccomputePermutationPolynomials(&grown)
+	computeSelectorsBigDomain(&grown)
+	computeQkIncompleteBigDomain(&grown)
+
+	if err := commitSetupPolynomials(&grown); err != nil {
+		return err
+	}
+
+	*pk.Vk = vk
+	pk.Domain = grown.Domain
+	pk.Ql, pk.Qr, pk.Qm, pk.Qo = grown.Ql, grown.Qr, grown.Qm, grown.Qo
+	pk.CQk, pk.LQk = grown.CQk, grown.LQk
+	pk.Permutation = grown.Permutation
+	pk.S1Canonical, pk.S2Canonical, pk.S3Canonical = grown.S1Canonical, grown.S2Canonical, grown.S3Canonical
+	pk.EvaluationPermutationBigDomainBitReversed = grown.EvaluationPermutationBigDomainBitReversed
+	pk.EvaluationSelectorsDomainBigBitReversed = grown.EvaluationSelectorsDomainBigBitReversed
+	pk.ZeroSelectors = grown.ZeroSelectors
+	pk.EvaluationQkIncompleteDomainBigBitReversed = grown.EvaluationQkIncompleteDomainBigBitReversed
+	return nil
+}
+
+// growPolynomial returns the canonical form of the polynomial on domain to whose values on the
+// first from.Cardinality points are the ones of p (in canonical form on from), and 0 elsewhere.
+func growPolynomial(p []fr.Element, from, to *fft.Domain) []fr.Element {
+	res := make([]fr.Element, to.Cardinality)
+	copy(res, p)
+	from.FFT(res[:from.Cardinality], fft.DIF)
+	fft.BitReverse(res[:from.Cardinality])
+	to.FFTInverse(res, fft.DIF)
+	fft.BitReverse(res)
+	return res
+}
+
+// setDomainCosetShift sets the representative of the coset of domain to cosetShift, along with
+// the coset tables used by domain.FFT and domain.FFTInverse. The tables are newly allocated, so
+// that a domain shared through getDomain is not modified.
+func setDomainCosetShift(domain *fft.Domain, cosetShift fr.Element) {
+	domain.FrMultiplicativeGen.Set(&cosetShift)
+	domain.FrMultiplicativeGenInv.Inverse(&cosetShift)
+
+	n := int(domain.Cardinality)
+	domain.CosetTable = make([]fr.Element, n)
+	domain.CosetTableInv = make([]fr.Element, n)
+	domain.CosetTable[0].SetOne()
+	domain.CosetTableInv[0].SetOne()
+	for i := 1; i < n; i++ {
+		domain.CosetTable[i].Mul(&domain.CosetTable[i-1], &domain.FrMultiplicativeGen)
+		domain.CosetTableInv[i].Mul(&domain.CosetTableInv[i-1], &domain.FrMultiplicativeGenInv)
+	}
+	domain.CosetTableReversed = make([]fr.Element, n)
+	domain.CosetTableInvReversed = make([]fr.Element, n)
+	copy(domain.CosetTableReversed, domain.CosetTable)
+	copy(domain.CosetTableInvReversed, domain.CosetTableInv)
+	fft.BitReverse(domain.CosetTableReversed)
+	fft.BitReverse(domain.CosetTableInvReversed)
+}
+
+// defaultCosetShift is the coset shift used by Setup, the multiplicative generator of fr
+// chosen by fft.NewDomain
+var defaultCosetShift = fft.NewDomain(1).FrMultiplicativeGen
+
+// PermutationPolys returns a copy of the permutation polynomials s1, s2, s3 in canonical form.
+//
+// Note that the VerifyingKey only stores the commitments to s1, s2, s3.
+func (pk *ProvingKey) PermutationPolys() (s1, s2, s3 []fr.Element) {
+	s1 = make([]fr.Element, len(pk.S1Canonical))
+	s2 = make([]fr.Element, len(pk.S2Canonical))
+	s3 = make([]fr.Element, len(pk.S3Canonical))
+	copy(s1, pk.S1Canonical)
+	copy(s2, pk.S2Canonical)
+	copy(s3, pk.S3Canonical)
+	return
+}
+
+// FreeScratch releases the evaluations of the permutation polynomials and of the incomplete qk
+// on the big domain (pk.EvaluationPermutationBigDomainBitReversed,
+// pk.EvaluationSelectorsDomainBigBitReversed and pk.EvaluationQkIncompleteDomainBigBitReversed),
+// which are recomputed from pk.S1Canonical, pk.S2Canonical, pk.S3Canonical, the selectors and
+// pk.CQk on the next call to Prove.
+//
+// This saves 8*Domain[1].Cardinality field elements while the key is idle, at the cost
+// of 8 FFTs on the big domain the next time it is used.
+//
+// FreeScratch must not be called concurrently with Prove. Concurrent calls to Prove which follow
+// it are safe, the first one recomputes the evaluations for all of them.
+func (pk *ProvingKey) FreeScratch() {
+	pk.EvaluationPermutationBigDomainBitReversed = nil
+	pk.EvaluationSelectorsDomainBigBitReversed = nil
+	pk.EvaluationQkIncompleteDomainBigBitReversed = nil
+}
+
+// restoreBigDomainEvaluations recomputes the evaluations of the permutation, the selectors and
+// qk on the big domain when they are missing: they are not serialized by WriteTo, and may have
+// been released by FreeScratch. The check and the computation happen under pk.scratchLock, the
+// first concurrent call to Prove does the work and the others wait for it.
+func (pk *ProvingKey) restoreBigDomainEvaluations() {
+	pk.scratchLock.Lock()
+	defer pk.scratchLock.Unlock()
+	if pk.EvaluationPermutationBigDomainBitReversed == nil {
+		computePermutationBigDomain(pk)
+	}
+	if pk.EvaluationSelectorsDomainBigBitReversed == nil {
+		computeSelectorsBigDomain(pk)
+	}
+	if pk.EvaluationQkIncompleteDomainBigBitReversed == nil {
+		computeQkIncompleteBigDomain(pk)
+	}
+}
+
 // VerifyingKey returns pk.Vk
 func (pk *ProvingKey) VerifyingKey() interface{} {
 	return pk.Vk
 }
+
+// isDomainUnderused returns true if the sizeSystem constraints (including the placeholders for
+// the public inputs) fill less than 60% of the domain of size cardinality
+func isDomainUnderused(sizeSystem, cardinality uint64) bool {
+	return 10*sizeSystem < 6*cardinality
+}
+
+// checkScalarField returns an error if spr was compiled for a curve whose scalar field is not fr.
+// The coefficients of such a constraint system have been reduced modulo another modulus.
+func checkScalarField(spr *cs.SparseR1CS) error {
+	id := spr.SparseR1CS.CurveID
+	if id == ecc.UNKNOWN {
+		// not built by the frontend, nothing to compare to
+		return nil
+	}
+	if id.Info().Fr.Modulus().Cmp(fr.Modulus()) != 0 {
+		return fmt.Errorf("%w: compiled for %s, expected %s", errScalarFieldMismatch, id, curve.ID)
+	}
+	return nil
+}
+
+// checkWireIDs returns an error if a constraint of spr references a wire ID which is not the ID
+// of one of its variables, the prover would index the solution out of range.
+func checkWireIDs(spr *cs.SparseR1CS) error {
+	nbVariables := spr.NbInternalVariables + spr.NbPublicVariables + spr.NbSecretVariables
+	for i, c := range spr.Constraints {
+		ids := [3]int{c.L.WireID(), c.R.WireID(), c.O.WireID()}
+		for j, id := range ids {
+			if id >= nbVariables {
+				return fmt.Errorf("%w: constraint %d, %c has wire ID %d, the system has %d variables", errWireOutOfRange, i, "LRO"[j], id, nbVariables)
+			}
+		}
+	}
+	return nil
+}
+
+// circuitDigest returns a sha256 digest of the parts of spr the proving key depends on: the number
+// of variables, the constraints and the coefficients. The debug information is not part of it.
+func circuitDigest(spr *cs.SparseR1CS) [32]byte {
+	h := sha256.New()
+	var buf [8]byte
+	writeUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+
+	writeUint64(uint64(spr.NbPublicVariables))
+	writeUint64(uint64(spr.NbSecretVariables))
+	writeUint64(uint64(spr.NbInternalVariables))
+
+	writeUint64(uint64(len(spr.Constraints)))
+	for _, c := range spr.Constraints {
+		writeUint64(uint64(c.L))
+		writeUint64(uint64(c.R))
+		writeUint64(uint64(c.O))
+		writeUint64(uint64(c.M[0]))
+		writeUint64(uint64(c.M[1]))
+		writeUint64(uint64(c.K))
+	}
+
+	writeUint64(uint64(len(spr.Coefficients)))
+	for i := 0; i < len(spr.Coefficients); i++ {
+		b := spr.Coefficients[i].Bytes()
+		h.Write(b[:])
+	}
+
+	var res [32]byte
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
+// checkCircuitDigest returns an error if spr is not the constraint system pk was set up with.
+// The proving keys of version 0 (see ProvingKey.ReadFrom) have no digest and aren't checked.
+func checkCircuitDigest(spr *cs.SparseR1CS, pk *ProvingKey) error {
+	if pk.CircuitDigest == ([32]byte{}) {
+		return nil
+	}
+	if circuitDigest(spr) != pk.CircuitDigest {
+		return errCircuitMismatch
+	}
+	return nil
+}
@@ -19,13 +19,33 @@ package plonk
 import (
 	curve "github.com/consensys/gnark-crypto/ecc/bw6-633"
 
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/consensys/gnark-crypto/ecc/bw6-633/fr"
 	"io"
 )
 
-// WriteTo writes binary encoding of Proof to w
+// proofVersion is the version of the binary encoding of Proof, written in its first byte.
+// It must be bumped whenever the encoding changes.
+const proofVersion byte = 1
+
+// ErrUnsupportedProofVersion is returned by Proof.ReadFrom when the encoded proof doesn't start
+// with the version of the encoding this package reads.
+var ErrUnsupportedProofVersion = errors.New("unsupported proof version")
+
+// WriteTo writes binary encoding of Proof to w, prefixed by the version of the encoding
 func (proof *Proof) WriteTo(w io.Writer) (int64, error) {
+	if _, err := w.Write([]byte{proofVersion}); err != nil {
+		return 0, err
+	}
+	n, err := proof.writeTo(w)
+	return n + 1, err
+}
+
+func (proof *Proof) writeTo(w io.Writer) (int64, error) {
 	enc := curve.NewEncoder(w)
 
 	toEncode := []interface{}{
@@ -49,12 +69,35 @@ func (proof *Proof) WriteTo(w io.Writer) (int64, error) {
 		return n + enc.BytesWritten(), err
 	}
 	n2, err := proof.ZShiftedOpening.WriteTo(w)
+	if err != nil {
+		return n + n2 + enc.BytesWritten(), err
+	}
 
-	return n + n2 + enc.BytesWritten(), err
+	// nonce, prefixed by its size
+	if err := binary.Write(w, binary.BigEndian, uint32(len(proof.Nonce))); err != nil {
+		return n + n2 + enc.BytesWritten(), err
+	}
+	n3, err := w.Write(proof.Nonce)
+
+	return n + n2 + enc.BytesWritten() + 4 + int64(n3), err
 }
 
 // ReadFrom reads binary representation of Proof from r
+//
+// It returns ErrUnsupportedProofVersion if the encoding isn't of the version written by WriteTo.
 func (proof *Proof) ReadFrom(r io.Reader) (int64, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+	if version[0] != proofVersion {
+		return 1, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedProofVersion, version[0], proofVersion)
+	}
+	n, err := proof.readFrom(r)
+	return n + 1, err
+}
+
+func (proof *Proof) readFrom(r io.Reader) (int64, error) {
 	dec := curve.NewDecoder(r)
 	toDecode := []interface{}{
 		&proof.LRO[0],
@@ -77,11 +120,247 @@ func (proof *Proof) ReadFrom(r io.Reader) (int64, error) {
 		return n + dec.BytesRead(), err
 	}
 	n2, err := proof.ZShiftedOpening.ReadFrom(r)
-	return n + n2 + dec.BytesRead(), err
+	if err != nil {
+		return n + n2 + dec.BytesRead(), err
+	}
+
+	var nonceSize uint32
+	if err := binary.Read(r, binary.BigEndian, &nonceSize); err != nil {
+		return n + n2 + dec.BytesRead(), err
+	}
+	if nonceSize > maxNonceSize {
+		return n + n2 + dec.BytesRead() + 4, fmt.Errorf("%w: %d bytes, at most %d", errNonceTooLarge, nonceSize, maxNonceSize)
+	}
+	proof.Nonce = nil
+	if nonceSize != 0 {
+		proof.Nonce = make([]byte, nonceSize)
+	}
+	n3, err := io.ReadFull(r, proof.Nonce)
+	return n + n2 + dec.BytesRead() + 4 + int64(n3), err
 }
 
-// WriteTo writes binary encoding of ProvingKey to w
-func (pk *ProvingKey) WriteTo(w io.Writer) (n int64, err error) {
+// maxNonceSize is the maximum size in bytes of the nonce of a proof, see backend.WithNonce
+const maxNonceSize = 1 << 10
+
+var errNonceTooLarge = errors.New("nonce is too large")
+
+// EstimateProofSize returns the size in bytes of the binary encoding (see Proof.WriteTo) of
+// a proof for vk, without proving.
+//
+// With KZG every part of the proof has a fixed size, independent of the size of the circuit:
+// the estimate is exact for a proof without nonce. A nonce set with backend.WithNonce adds its
+// length (at most 1KiB), and a proof generated with backend.WithCommitOnly is smaller.
+func EstimateProofSize(vk *VerifyingKey) int {
+	const (
+		nbPoints  = 9 // l, r, o, z, h1, h2, h3, and the two opening proofs
+		nbScalars = nbWitnessElements
+	)
+	// the proof is prefixed by its version, the claimed values of the batched opening and the
+	// nonce by their length
+	return 1 + nbPoints*curve.SizeOfG1AffineCompressed + nbScalars*fr.Bytes + 4 + 4
+}
+
+// HexMap returns the hex encoding of each field of the proof, indexed by the field name
+// (e.g. "LRO[1]", "BatchedProof.ClaimedValues[3]"). Points are encoded in compressed form
+// and field elements as big endian integers (regular form).
+//
+// This is meant for loosely-typed transport layers, see SetHexMap for the reverse operation.
+func (proof *Proof) HexMap() map[string]string {
+	m := make(map[string]string)
+	for name, p := range proof.points() {
+		b := p.Bytes()
+		m[name] = hex.EncodeToString(b[:])
+	}
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		b := proof.BatchedProof.ClaimedValues[i].Bytes()
+		m[fmt.Sprintf("BatchedProof.ClaimedValues[%d]", i)] = hex.EncodeToString(b[:])
+	}
+	b := proof.ZShiftedOpening.ClaimedValue.Bytes()
+	m["ZShiftedOpening.ClaimedValue"] = hex.EncodeToString(b[:])
+	if len(proof.Nonce) != 0 {
+		m["Nonce"] = hex.EncodeToString(proof.Nonce)
+	}
+	return m
+}
+
+// SetHexMap sets the proof from the hex encodings returned by HexMap.
+//
+// It returns an error if an entry is missing, or doesn't decode to a valid point or
+// to a canonical field element.
+func (proof *Proof) SetHexMap(m map[string]string) error {
+	for name, p := range proof.points() {
+		s, ok := m[name]
+		if !ok {
+			return fmt.Errorf("missing %s", name)
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		n, err := p.SetBytes(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if n != len(b) {
+			return fmt.Errorf("%s: invalid point encoding size", name)
+		}
+	}
+
+	proof.BatchedProof.ClaimedValues = proof.BatchedProof.ClaimedValues[:0]
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("BatchedProof.ClaimedValues[%d]", i)
+		s, ok := m[name]
+		if !ok {
+			break
+		}
+		var e fr.Element
+		if err := setHexElement(&e, s); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		proof.BatchedProof.ClaimedValues = append(proof.BatchedProof.ClaimedValues, e)
+	}
+
+	s, ok := m["ZShiftedOpening.ClaimedValue"]
+	if !ok {
+		return errors.New("missing ZShiftedOpening.ClaimedValue")
+	}
+	if err := setHexElement(&proof.ZShiftedOpening.ClaimedValue, s); err != nil {
+		return fmt.Errorf("ZShiftedOpening.ClaimedValue: %w", err)
+	}
+
+	// the nonce is optional
+	proof.Nonce = nil
+	if s, ok := m["Nonce"]; ok {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("Nonce: %w", err)
+		}
+		if len(b) > maxNonceSize {
+			return fmt.Errorf("Nonce: %w", errNonceTooLarge)
+		}
+		if len(b) != 0 {
+			proof.Nonce = b
+		}
+	}
+
+	return nil
+}
+
+// nbWitnessElements is the size of the vector returned by Proof.ToWitness
+const nbWitnessElements = 8
+
+// ToWitness returns the scalar field elements of the proof, in the order expected by an
+// in-circuit verifier:
+//
+//	[0] h(ζ) (folded h1 + ζᵐ*h2 + ζ²ᵐ*h3)
+//	[1] linearizedPolynomial(ζ)
+//	[2] l(ζ), [3] r(ζ), [4] o(ζ)
+//	[5] s1(ζ), [6] s2(ζ)
+//	[7] z(μζ)
+//
+// The commitments and opening proofs are points of the curve, whose coordinates are not
+// elements of fr: they are not part of the witness. The result always has 8 elements, a proof
+// generated with backend.WithCommitOnly has no openings and yields zeros.
+func (proof *Proof) ToWitness() []fr.Element {
+	res := make([]fr.Element, nbWitnessElements)
+	copy(res[:nbWitnessElements-1], proof.BatchedProof.ClaimedValues)
+	res[nbWitnessElements-1] = proof.ZShiftedOpening.ClaimedValue
+	return res
+}
+
+// Equal returns true if proof and other have the same commitments, opening proofs and claimed
+// values.
+func (proof *Proof) Equal(other *Proof) bool {
+	return proof.Diff(other) == ""
+}
+
+// Diff returns the name of the first field which differs between proof and other, for instance
+// "BatchedProof.ClaimedValues[1]", or "" if the proofs are equal.
+func (proof *Proof) Diff(other *Proof) string {
+	if proof == nil || other == nil {
+		if proof == other {
+			return ""
+		}
+		return "Proof"
+	}
+
+	p, q := proof.points(), other.points()
+	for _, name := range proofPointNames {
+		if !p[name].Equal(q[name]) {
+			return name
+		}
+	}
+
+	if len(proof.BatchedProof.ClaimedValues) != len(other.BatchedProof.ClaimedValues) {
+		return "len(BatchedProof.ClaimedValues)"
+	}
+	for i := 0; i < len(proof.BatchedProof.ClaimedValues); i++ {
+		if !proof.BatchedProof.ClaimedValues[i].Equal(&other.BatchedProof.ClaimedValues[i]) {
+			return fmt.Sprintf("BatchedProof.ClaimedValues[%d]", i)
+		}
+	}
+
+	if !proof.ZShiftedOpening.ClaimedValue.Equal(&other.ZShiftedOpening.ClaimedValue) {
+		return "ZShiftedOpening.ClaimedValue"
+	}
+
+	if !bytes.Equal(proof.Nonce, other.Nonce) {
+		return "Nonce"
+	}
+
+	return ""
+}
+
+// proofPointNames lists the keys of Proof.points in the order of the fields of Proof
+var proofPointNames = []string{
+	"LRO[0]", "LRO[1]", "LRO[2]",
+	"Z",
+	"H[0]", "H[1]", "H[2]",
+	"BatchedProof.H",
+	"ZShiftedOpening.H",
+}
+
+// points returns pointers to the points of the proof, indexed by field name
+func (proof *Proof) points() map[string]*curve.G1Affine {
+	return map[string]*curve.G1Affine{
+		"LRO[0]":            &proof.LRO[0],
+		"LRO[1]":            &proof.LRO[1],
+		"LRO[2]":            &proof.LRO[2],
+		"Z":                 &proof.Z,
+		"H[0]":              &proof.H[0],
+		"H[1]":              &proof.H[1],
+		"H[2]":              &proof.H[2],
+		"BatchedProof.H":    &proof.BatchedProof.H,
+		"ZShiftedOpening.H": &proof.ZShiftedOpening.H,
+	}
+}
+
+// setHexElement sets e from the hex encoding of a canonical field element (big endian)
+func setHexElement(e *fr.Element, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != fr.Bytes {
+		return fmt.Errorf("invalid field element size, got %d bytes, expected %d", len(b), fr.Bytes)
+	}
+	e.SetBytes(b)
+	if c := e.Bytes(); !bytes.Equal(c[:], b) {
+		return errors.New("field element is not canonical")
+	}
+	return nil
+}
+
+// WriteTo writes binary encoding of ProvingKey to w, prefixed by the version of the encoding
+func (pk *ProvingKey) WriteTo(w io.Writer) (int64, error) {
+	if _, err := w.Write([]byte{keyVersion}); err != nil {
+		return 0, err
+	}
+	n, err := pk.writeTo(w)
+	return n + 1, err
+}
+
+func (pk *ProvingKey) writeTo(w io.Writer) (n int64, err error) {
 	// encode the verifying key
 	n, err = pk.Vk.WriteTo(w)
 	if err != nil {
@@ -121,6 +400,7 @@ func (pk *ProvingKey) WriteTo(w io.Writer) (n int64, err error) {
 		([]fr.Element)(pk.S2Canonical),
 		([]fr.Element)(pk.S3Canonical),
 		pk.Permutation,
+		&pk.CircuitDigest,
 	}
 
 	for _, v := range toEncode {
@@ -133,7 +413,29 @@ func (pk *ProvingKey) WriteTo(w io.Writer) (n int64, err error) {
 }
 
 // ReadFrom reads from binary representation in r into ProvingKey
+//
+// It reads the encodings of version 0 as well, their circuit digest is zero and isn't checked by
+// the prover. Their permutation puts the entries of l, r, o which aren't the wire of a constraint
+// in the cycle of wire 0, which the prover follows. It returns ErrUnsupportedKeyVersion if the
+// encoding is of a later version than the one written by WriteTo.
 func (pk *ProvingKey) ReadFrom(r io.Reader) (int64, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+	switch version[0] {
+	case keyVersion:
+		n, err := pk.readFrom(r, true)
+		return n + 1, err
+	case 0:
+		// the version byte is the first byte of the verifying key of version 0
+		return pk.readFrom(io.MultiReader(bytes.NewReader(version[:]), r), false)
+	default:
+		return 1, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedKeyVersion, version[0], keyVersion)
+	}
+}
+
+func (pk *ProvingKey) readFrom(r io.Reader, withCircuitDigest bool) (int64, error) {
 	pk.Vk = &VerifyingKey{}
 	n, err := pk.Vk.ReadFrom(r)
 	if err != nil {
@@ -167,6 +469,11 @@ func (pk *ProvingKey) ReadFrom(r io.Reader) (int64, error) {
 		(*[]fr.Element)(&pk.S3Canonical),
 		&pk.Permutation,
 	}
+	if withCircuitDigest {
+		toDecode = append(toDecode, &pk.CircuitDigest)
+	} else {
+		pk.CircuitDigest = [32]byte{}
+	}
 
 	for _, v := range toDecode {
 		if err := dec.Decode(v); err != nil {
@@ -178,8 +485,27 @@ func (pk *ProvingKey) ReadFrom(r io.Reader) (int64, error) {
 
 }
 
-// WriteTo writes binary encoding of VerifyingKey to w
-func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
+// keyVersion is the version of the binary encoding of the keys, written in their first byte.
+// It must be bumped whenever the encoding changes.
+//
+// Version 0 is the encoding without version byte, vk.CosetShift nor pk.CircuitDigest, its first
+// byte is the most significant byte of vk.Size, which is zero.
+const keyVersion byte = 1
+
+// ErrUnsupportedKeyVersion is returned by ProvingKey.ReadFrom and VerifyingKey.ReadFrom when the
+// encoded key is of a version this package doesn't read.
+var ErrUnsupportedKeyVersion = errors.New("unsupported key version")
+
+// WriteTo writes binary encoding of VerifyingKey to w, prefixed by the version of the encoding
+func (vk *VerifyingKey) WriteTo(w io.Writer) (int64, error) {
+	if _, err := w.Write([]byte{keyVersion}); err != nil {
+		return 0, err
+	}
+	n, err := vk.writeTo(w)
+	return n + 1, err
+}
+
+func (vk *VerifyingKey) writeTo(w io.Writer) (n int64, err error) {
 	enc := curve.NewEncoder(w)
 
 	toEncode := []interface{}{
@@ -195,6 +521,7 @@ func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
 		&vk.Qm,
 		&vk.Qo,
 		&vk.Qk,
+		&vk.CosetShift,
 	}
 
 	for _, v := range toEncode {
@@ -207,7 +534,27 @@ func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
 }
 
 // ReadFrom reads from binary representation in r into VerifyingKey
+//
+// It reads the encodings of version 0 as well, their coset shift is the one of Setup. It returns
+// ErrUnsupportedKeyVersion if the encoding is of a later version than the one written by WriteTo.
 func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+	switch version[0] {
+	case keyVersion:
+		n, err := vk.readFrom(r, true)
+		return n + 1, err
+	case 0:
+		// the version byte is the first byte of vk.Size
+		return vk.readFrom(io.MultiReader(bytes.NewReader(version[:]), r), false)
+	default:
+		return 1, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedKeyVersion, version[0], keyVersion)
+	}
+}
+
+func (vk *VerifyingKey) readFrom(r io.Reader, withCosetShift bool) (int64, error) {
 	dec := curve.NewDecoder(r)
 	toDecode := []interface{}{
 		&vk.Size,
@@ -223,6 +570,11 @@ func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
 		&vk.Qo,
 		&vk.Qk,
 	}
+	if withCosetShift {
+		toDecode = append(toDecode, &vk.CosetShift)
+	} else {
+		vk.CosetShift.Set(&defaultCosetShift)
+	}
 
 	for _, v := range toDecode {
 		if err := dec.Decode(v); err != nil {
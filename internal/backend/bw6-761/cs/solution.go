@@ -39,6 +39,7 @@ type solution struct {
 	values, coefficients []fr.Element
 	solved               []bool
 	nbSolved             uint64
+	nbCheckOnly          uint64                    // number of constraints which didn't instantiate a wire (SparseR1CS)
 	mHintsFunctions      map[hint.ID]hint.Function // maps hintID to hint function
 	mHints               map[int]*compiled.Hint    // maps wireID to hint
 }
@@ -0,0 +1,41 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark/frontend"
+)
+
+// copyChainLength is the number of links in the copyChain circuit.
+const copyChainLength = 64
+
+// copyChain builds long copy constraint cycles spanning the l, r and o
+// columns: x² is the output of one gate and an input (left and right) of
+// every link, and each link is the output of a gate and the input of the next.
+type copyChain struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *copyChain) Define(api frontend.API) error {
+	x2 := api.Mul(circuit.X, circuit.X)
+	v := x2
+	for i := 0; i < copyChainLength; i++ {
+		p := api.Mul(v, x2)
+		s := api.Mul(x2, p)
+		v = api.Add(s, x2)
+	}
+	api.AssertIsEqual(v, circuit.Y)
+	return nil
+}
+
+func init() {
+	var circuit, good, bad copyChain
+
+	good.X = (1)
+	good.Y = (copyChainLength + 1)
+
+	bad.X = (1)
+	bad.Y = (copyChainLength + 2)
+
+	addEntry("copy_chain", &circuit, &good, &bad, gnark.Curves())
+}
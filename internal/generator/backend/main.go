@@ -127,6 +127,8 @@ func main() {
 				{File: filepath.Join(plonkDir, "setup.go"), Templates: []string{"plonk/plonk.setup.go.tmpl", importCurve}},
 				{File: filepath.Join(plonkDir, "marshal.go"), Templates: []string{"plonk/plonk.marshal.go.tmpl", importCurve}},
 				{File: filepath.Join(plonkDir, "marshal_test.go"), Templates: []string{"plonk/tests/marshal.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkDir, "testvectors_test.go"), Templates: []string{"plonk/tests/testvectors.go.tmpl", importCurve}},
+				{File: filepath.Join(plonkDir, "prove_test.go"), Templates: []string{"plonk/tests/prove.go.tmpl", importCurve}},
 			}
 			if err := bgen.Generate(d, "plonk", "./template/zkpschemes/", entries...); err != nil {
 				panic(err)
@@ -6,12 +6,17 @@ import (
 )
 
 // Parallelize process in parallel the work function
+//
+// maxCpus, if set, is the number of tasks; values below 1 are treated as 1.
 func Parallelize(nbIterations int, work func(int, int), maxCpus ...int) {
 
 	nbTasks := runtime.NumCPU()
 	if len(maxCpus) == 1 {
 		nbTasks = maxCpus[0]
 	}
+	if nbTasks < 1 {
+		nbTasks = 1
+	}
 	nbIterationsPerCpus := nbIterations / nbTasks
 
 	// more CPUs than tasks: a CPU will work on exactly one iteration
@@ -0,0 +1,39 @@
+// Copyright 2020 ConsenSys AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelizeNbTasks(t *testing.T) {
+	// runtime.NumCPU()/2 is 0 on a single core machine
+	for _, nbTasks := range []int{-1, 0, 1, runtime.NumCPU() / 2, 3} {
+		const nbIterations = 100
+		var done [nbIterations]int32
+		Parallelize(nbIterations, func(start, end int) {
+			for i := start; i < end; i++ {
+				atomic.AddInt32(&done[i], 1)
+			}
+		}, nbTasks)
+		for i := range done {
+			if done[i] != 1 {
+				t.Fatalf("%d tasks: iteration %d done %d times", nbTasks, i, done[i])
+			}
+		}
+	}
+}